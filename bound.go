@@ -0,0 +1,230 @@
+package sm2elgamal
+
+import (
+	"context"
+	"crypto/elliptic"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+)
+
+var (
+	// ErrBoundExceeded is returned when the bound of the plaintext of a
+	// ciphertext leaves the range it can be decrypted in.
+	ErrBoundExceeded = errors.New("plaintext bound exceeds the decryptable range")
+	// ErrInvalidBound is returned when the lower bound is greater than the upper bound.
+	ErrInvalidBound = errors.New("invalid plaintext bound")
+)
+
+// interval is the closed interval [lo, hi].
+type interval struct {
+	lo, hi *big.Int
+}
+
+func newInterval(lo, hi int64) *interval {
+	return &interval{big.NewInt(lo), big.NewInt(hi)}
+}
+
+var (
+	uint32Range = newInterval(0, math.MaxUint32)
+	int32Range  = newInterval(-math.MaxInt32, math.MaxInt32)
+	// searchableRange is what the sessions can search in both directions.
+	searchableRange = &interval{
+		new(big.Int).Neg(new(big.Int).SetUint64(math.MaxUint64)),
+		new(big.Int).SetUint64(math.MaxUint64),
+	}
+)
+
+// decryptableRange returns the range a ciphertext of the plaintext type can be decrypted in.
+func decryptableRange(ptype PlaintextType) *interval {
+	switch ptype {
+	case PlaintextUint32:
+		return uint32Range
+	case PlaintextInt32:
+		return int32Range
+	}
+	return searchableRange
+}
+
+// add returns a + b, nil if either is unknown.
+func (a *interval) add(b *interval) *interval {
+	if a == nil || b == nil {
+		return nil
+	}
+	return &interval{new(big.Int).Add(a.lo, b.lo), new(big.Int).Add(a.hi, b.hi)}
+}
+
+func (a *interval) neg() *interval {
+	if a == nil {
+		return nil
+	}
+	return &interval{new(big.Int).Neg(a.hi), new(big.Int).Neg(a.lo)}
+}
+
+func (a *interval) mul(k *big.Int) *interval {
+	if a == nil {
+		return nil
+	}
+	if k.Sign() < 0 {
+		a, k = a.neg(), new(big.Int).Neg(k)
+	}
+	return &interval{new(big.Int).Mul(a.lo, k), new(big.Int).Mul(a.hi, k)}
+}
+
+// div returns the bound of the exact quotients of the multiples of k in a.
+func (a *interval) div(k *big.Int) *interval {
+	if a == nil {
+		return nil
+	}
+	if k.Sign() < 0 {
+		a, k = a.neg(), new(big.Int).Neg(k)
+	}
+	// ceil(lo / k) and floor(hi / k), big.Int.Div rounds down for positive k
+	lo := new(big.Int).Neg(new(big.Int).Div(new(big.Int).Neg(a.lo), k))
+	return &interval{lo, new(big.Int).Div(a.hi, k)}
+}
+
+// intersect returns the intersection of a and b, a nil a is unknown so b is
+// returned. The result is nil if it is empty.
+func (a *interval) intersect(b *interval) *interval {
+	if a == nil {
+		return b
+	}
+	ret := &interval{a.lo, a.hi}
+	if b.lo.Cmp(ret.lo) > 0 {
+		ret.lo = b.lo
+	}
+	if b.hi.Cmp(ret.hi) < 0 {
+		ret.hi = b.hi
+	}
+	if ret.lo.Cmp(ret.hi) > 0 {
+		return nil
+	}
+	return ret
+}
+
+func (a *interval) contains(v *big.Int) bool {
+	return a.lo.Cmp(v) <= 0 && v.Cmp(a.hi) <= 0
+}
+
+// checkBound returns ErrBoundExceeded if the bound of c leaves its decryptable range.
+func (c *Ciphertext) checkBound() error {
+	if c.bound == nil {
+		return nil
+	}
+	r := decryptableRange(c.ptype)
+	if !r.contains(c.bound.lo) || !r.contains(c.bound.hi) {
+		return ErrBoundExceeded
+	}
+	return nil
+}
+
+// Bound returns the public bound [lo, hi] of the plaintext, ok is false if it is unknown.
+func (c *Ciphertext) Bound() (lo, hi *big.Int, ok bool) {
+	if c.bound == nil {
+		return nil, nil, false
+	}
+	return new(big.Int).Set(c.bound.lo), new(big.Int).Set(c.bound.hi), true
+}
+
+// WithBound returns a copy of c carrying the bound [lo, hi] of its plaintext.
+//
+// The bound is public, it is propagated through [Add], [Sum], [Sub], [Neg],
+// [AddPlain], [ScalarMult] and [DivExact] by interval arithmetic, they return
+// ErrBoundExceeded as soon as it leaves the range the result can be decrypted
+// in: [0, 2^32) for uint32 ciphertexts and (-2^31, 2^31) for int32 ones.
+// The decryption only searches the bound. The result of an operation with a
+// ciphertext without bound has no bound. The bound is not serialized.
+func WithBound(c *Ciphertext, lo, hi *big.Int) (*Ciphertext, error) {
+	if _, _, _, _, err := c.points(); err != nil {
+		return nil, err
+	}
+	if lo == nil || hi == nil || lo.Cmp(hi) > 0 {
+		return nil, ErrInvalidBound
+	}
+	ret := *c
+	ret.bound = &interval{new(big.Int).Set(lo), new(big.Int).Set(hi)}
+	if err := ret.checkBound(); err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// EncryptBounded encrypts m with the public key of any scheme, the ciphertext
+// carries the bound [lo, hi] of m, see [WithBound].
+func EncryptBounded(random io.Reader, pub PublicKey, m, lo, hi *big.Int) (*Ciphertext, error) {
+	if lo == nil || hi == nil || lo.Cmp(hi) > 0 {
+		return nil, ErrInvalidBound
+	}
+	if m.Cmp(lo) < 0 || m.Cmp(hi) > 0 {
+		return nil, ErrOutOfRange
+	}
+	c, err := pub.Encrypt(random, m)
+	if err != nil {
+		return nil, err
+	}
+	return WithBound(c, lo, hi)
+}
+
+// searchInterval finds m in r with (x, y) = mG, it returns ErrOverflow if
+// r is nil or m is not in r. The time depends on the width of r rather
+// than its position if r doesn't contain zero.
+func searchInterval(ctx context.Context, curve elliptic.Curve, x, y *big.Int, r *interval) (*big.Int, error) {
+	if r == nil {
+		return nil, ErrOverflow
+	}
+	offset := new(big.Int)
+	if r.lo.Sign() > 0 || r.hi.Sign() < 0 {
+		// search (m - lo)G in [0, hi - lo] if r doesn't contain zero
+		offset.Set(r.lo)
+		ox, oy := curve.ScalarBaseMult(new(big.Int).Mod(new(big.Int).Neg(offset), curve.Params().N).Bytes())
+		x, y = curve.Add(x, y, ox, oy)
+	}
+	var posLimit, negLimit uint64
+	if hi := new(big.Int).Sub(r.hi, offset); hi.Sign() >= 0 {
+		posLimit = searchLimit(hi)
+	}
+	if lo := new(big.Int).Sub(r.lo, offset); lo.Sign() < 0 {
+		negLimit = searchLimit(lo.Neg(lo))
+	}
+	v, negative, err := searchRange(ctx, curve, x, y, posLimit, negLimit)
+	if err != nil {
+		return nil, err
+	}
+	m := new(big.Int).SetUint64(v)
+	if negative {
+		m.Neg(m)
+	}
+	m.Add(m, offset)
+	if !r.contains(m) {
+		return nil, ErrOverflow
+	}
+	return m, nil
+}
+
+// searchLimit returns v + 1 clamped to uint64.
+func searchLimit(v *big.Int) uint64 {
+	if v.Cmp(searchableRange.hi) >= 0 {
+		return math.MaxUint64
+	}
+	return v.Uint64() + 1
+}
+
+// searchUint32Bounded finds m in the bound b, see [searchUint32].
+func searchUint32Bounded(curve elliptic.Curve, x, y *big.Int, b *interval) (uint32, error) {
+	m, err := searchInterval(context.Background(), curve, x, y, b.intersect(uint32Range))
+	if err != nil {
+		return 0, err
+	}
+	return uint32(m.Uint64()), nil
+}
+
+// searchInt32Bounded finds m in the bound b, see [searchInt32].
+func searchInt32Bounded(curve elliptic.Curve, x, y *big.Int, b *interval) (int32, error) {
+	m, err := searchInterval(context.Background(), curve, x, y, b.intersect(int32Range))
+	if err != nil {
+		return 0, err
+	}
+	return int32(m.Int64()), nil
+}
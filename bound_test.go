@@ -0,0 +1,157 @@
+package sm2elgamal
+
+import (
+	"crypto/rand"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func checkBound(t *testing.T, c *Ciphertext, lo, hi int64) {
+	t.Helper()
+	l, h, ok := c.Bound()
+	if !ok || l.Int64() != lo || h.Int64() != hi {
+		t.Fatalf("expected bound [%d, %d], got [%v, %v], %v", lo, hi, l, h, ok)
+	}
+}
+
+func TestBoundPropagation(t *testing.T) {
+	std, _ := sm2.GenerateKey(rand.Reader)
+	pub := NewStandardSecretKey(std).Public()
+	a, err := EncryptBounded(rand.Reader, pub, big.NewInt(10), big.NewInt(0), big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := EncryptBounded(rand.Reader, pub, big.NewInt(-3), big.NewInt(-5), big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBound(t, a, 0, 100)
+
+	sum, err := Add(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBound(t, sum, -5, 105)
+	diff, err := Sub(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBound(t, diff, -5, 105)
+	neg, err := Neg(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBound(t, neg, -105, 5)
+	prod, err := ScalarMult(neg, big.NewInt(-2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBound(t, prod, -10, 210)
+	quot, err := DivExact(prod, big.NewInt(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBound(t, quot, -5, 105)
+	plain, err := AddPlain(quot, big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBound(t, plain, 0, 110)
+	total, err := Sum(plain, a, TrivialEncrypt(big.NewInt(7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBound(t, total, 7, 217)
+	if total, err = Rerandomize(rand.Reader, &std.PublicKey, total); err != nil {
+		t.Fatal(err)
+	}
+	checkBound(t, total, 7, 217)
+	// 13 + 5 + 10 + 7
+	if v, err := DecryptUint32(std, total); err != nil || v != 35 {
+		t.Fatalf("expected 35, got %v, %v", v, err)
+	}
+	if v, err := NewDecryptor().DecryptInt32(NewPrivateKey(std), total); err != nil || v != 35 {
+		t.Fatalf("expected 35, got %v, %v", v, err)
+	}
+
+	// no bound once combined with a ciphertext without bound
+	c, _ := EncryptUint32(rand.Reader, &std.PublicKey, 1)
+	if sum, err = Add(a, c); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := sum.Bound(); ok {
+		t.Fatal("should have no bound")
+	}
+}
+
+func TestBoundExceeded(t *testing.T) {
+	c, err := priv.EncryptUint32(rand.Reader, 0xfffffff0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = WithBound(c, big.NewInt(-1), big.NewInt(10)); err != ErrBoundExceeded {
+		t.Fatalf("expected ErrBoundExceeded, got %v", err)
+	}
+	if _, err = WithBound(c, big.NewInt(10), big.NewInt(1)); err != ErrInvalidBound {
+		t.Fatalf("expected ErrInvalidBound, got %v", err)
+	}
+	if c, err = WithBound(c, big.NewInt(0xfffffff0), big.NewInt(math.MaxUint32)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Add(c, c); err != ErrBoundExceeded {
+		t.Fatalf("expected ErrBoundExceeded, got %v", err)
+	}
+	if _, err = ScalarMult(c, big.NewInt(2)); err != ErrBoundExceeded {
+		t.Fatalf("expected ErrBoundExceeded, got %v", err)
+	}
+	// the negation of uint32 has no type, so it can be negative
+	if n, err := Neg(c); err != nil || n.PlaintextType() != 0 {
+		t.Fatalf("expected untyped negation, got %v", err)
+	}
+	if _, err = AddPlain(c, big.NewInt(0x10)); err != ErrBoundExceeded {
+		t.Fatalf("expected ErrBoundExceeded, got %v", err)
+	}
+	if d, err := Sub(c, c); err != nil || d.PlaintextType() != 0 {
+		t.Fatalf("expected untyped difference, got %v", err)
+	}
+	if v, err := priv.DecryptUint32(c); err != nil || v != 0xfffffff0 {
+		t.Fatalf("expected 0xfffffff0, got %v, %v", v, err)
+	}
+	if _, err = EncryptBounded(rand.Reader, NewTwistedSecretKey(priv).Public(), big.NewInt(11), big.NewInt(0), big.NewInt(10)); err != ErrOutOfRange {
+		t.Fatalf("expected ErrOutOfRange, got %v", err)
+	}
+}
+
+func TestBoundNarrowsSearch(t *testing.T) {
+	sk := NewTwistedSecretKey(priv)
+	// far beyond uint32, decrypted in a single giant step thanks to the bound
+	m := new(big.Int).Lsh(big.NewInt(1), 60)
+	lo := new(big.Int).Sub(m, big.NewInt(100))
+	c, err := EncryptBounded(rand.Reader, sk.Public(), m, lo, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typed, err := NewTypedCiphertext[int64](c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := typed.Decrypt(sk); err != nil || v != 1<<60 {
+		t.Fatalf("expected 2^60, got %v, %v", v, err)
+	}
+	typed32, err := NewTypedCiphertext[int32](c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = typed32.Decrypt(sk); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+	if v, err := DecryptEncoded(sk, IntEncoder{Min: -100, Max: 1 << 50}, c); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow, got %v, %v", v, err)
+	}
+	if v, err := DecryptEncoded(sk, IntEncoder{Min: 0, Max: math.MaxInt64}, c); err != nil || v != 1<<60 {
+		t.Fatalf("expected 2^60, got %v, %v", v, err)
+	}
+}
@@ -0,0 +1,74 @@
+package sm2elgamal
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CacheStats reports the usage of a decryption cache.
+type CacheStats struct {
+	Hits   uint64 // number of lookups answered by the cache
+	Misses uint64 // number of lookups that needed a search
+	Len    int    // number of cached points
+}
+
+// pointCache is a bounded LRU cache from m*G (compressed form) to m.
+type pointCache struct {
+	mu     sync.Mutex
+	size   int
+	ll     *list.List
+	items  map[string]*list.Element
+	hits   uint64
+	misses uint64
+}
+
+type pointCacheEntry struct {
+	key   string
+	value int64
+}
+
+func newPointCache(size int) *pointCache {
+	return &pointCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached value of key if it is in [min, max].
+func (c *pointCache) get(key string, min, max int64) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		v := e.Value.(*pointCacheEntry).value
+		if v >= min && v <= max {
+			c.ll.MoveToFront(e)
+			c.hits++
+			return v, true
+		}
+	}
+	c.misses++
+	return 0, false
+}
+
+func (c *pointCache) put(key string, value int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		e.Value.(*pointCacheEntry).value = value
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&pointCacheEntry{key, value})
+	if c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*pointCacheEntry).key)
+	}
+}
+
+func (c *pointCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Len: c.ll.Len()}
+}
@@ -0,0 +1,66 @@
+package sm2elgamal
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"math/big"
+)
+
+// NewCiphertext creates a ciphertext from its two points, which can be in
+// SEC 1 compressed or uncompressed form, and a single zero byte stands for
+// the point at infinity. For the standard scheme c1 = rG and c2 = rP + mG,
+// for the Twisted scheme c1 = rP and c2 = rH + mG.
+func NewCiphertext(curve elliptic.Curve, c1, c2 []byte) (*Ciphertext, error) {
+	if curve == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	x1, y1, err := parsePoint(curve, c1)
+	if err != nil {
+		return nil, err
+	}
+	x2, y2, err := parsePoint(curve, c2)
+	if err != nil {
+		return nil, err
+	}
+	return newCiphertext(curve, x1, y1, x2, y2), nil
+}
+
+// parsePoint is like unmarshalPoint but accepts the uncompressed form too.
+func parsePoint(curve elliptic.Curve, data []byte) (x, y *big.Int, err error) {
+	if len(data) > 0 && data[0] == 4 {
+		if x, y = elliptic.Unmarshal(curve, data); x == nil {
+			return nil, nil, ErrInvalidCiphertext
+		}
+		return x, y, nil
+	}
+	return unmarshalPoint(curve, data)
+}
+
+// Curve returns the curve of the ciphertext.
+func (c *Ciphertext) Curve() elliptic.Curve {
+	return c.curve
+}
+
+// C1 returns the first point of the ciphertext, (0, 0) is the point at infinity.
+func (c *Ciphertext) C1() (x, y *big.Int, err error) {
+	x, y, _, _, err = c.points()
+	return
+}
+
+// C2 returns the second point of the ciphertext, (0, 0) is the point at infinity.
+func (c *Ciphertext) C2() (x, y *big.Int, err error) {
+	_, _, x, y, err = c.points()
+	return
+}
+
+// C1Bytes returns the first point of the ciphertext in compressed form,
+// a single zero byte is the point at infinity.
+func (c *Ciphertext) C1Bytes() []byte {
+	return bytes.Clone(c.c1)
+}
+
+// C2Bytes returns the second point of the ciphertext in compressed form,
+// a single zero byte is the point at infinity.
+func (c *Ciphertext) C2Bytes() []byte {
+	return bytes.Clone(c.c2)
+}
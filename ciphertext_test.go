@@ -0,0 +1,53 @@
+package sm2elgamal
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func TestNewCiphertext(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	c, err := EncryptUint32(rand.Reader, &priv.PublicKey, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x1, y1, err := c.C1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	x2, y2, err := c.C2()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := NewCiphertext(c.Curve(), elliptic.Marshal(c.Curve(), x1, y1), elliptic.Marshal(c.Curve(), x2, y2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c2.C1Bytes(), c.C1Bytes()) || !bytes.Equal(c2.C2Bytes(), c.C2Bytes()) {
+		t.Fatal("not same")
+	}
+	v, err := DecryptUint32(priv, c2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 100 {
+		t.Fatalf("expected %d, got %d", 100, v)
+	}
+
+	if _, err = NewCiphertext(nil, c.C1Bytes(), c.C2Bytes()); err != ErrInvalidCiphertext {
+		t.Errorf("expected ErrInvalidCiphertext, got %v", err)
+	}
+	if _, err = NewCiphertext(c.Curve(), c.C1Bytes(), c.C2Bytes()[1:]); err != ErrInvalidCiphertext {
+		t.Errorf("expected ErrInvalidCiphertext, got %v", err)
+	}
+	if _, err = NewCiphertext(c.Curve(), []byte{0}, []byte{}); err != ErrInvalidCiphertext {
+		t.Errorf("expected ErrInvalidCiphertext, got %v", err)
+	}
+	if _, _, err = new(Ciphertext).C1(); err != ErrInvalidCiphertext {
+		t.Errorf("expected ErrInvalidCiphertext, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package sm2elgamal
+
+import (
+	"context"
+	"crypto"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+// PlaintextType is the integer type of a plaintext.
+type PlaintextType uint8
+
+const (
+	// PlaintextUint32 is a uint32 plaintext.
+	PlaintextUint32 PlaintextType = iota + 1
+	// PlaintextInt32 is an int32 plaintext.
+	PlaintextInt32
+	// PlaintextRange is a plaintext in [0, Limit), Limit can be larger than 2^32.
+	PlaintextRange
+)
+
+// ErrInvalidDecrypterOpts is returned when the options of [Decrypter.Decrypt] are invalid.
+var ErrInvalidDecrypterOpts = errors.New("invalid decrypter options")
+
+// DecrypterOpts selects the plaintext type of [Decrypter].
+type DecrypterOpts struct {
+	Type  PlaintextType
+	Limit uint64 // the exclusive upper bound of PlaintextRange
+	// Context bounds the search of PlaintextRange, which takes long for a
+	// large Limit, it is checked between giant steps. Nil means no bound.
+	Context context.Context
+}
+
+// Decrypter implements [crypto.Decrypter] with an ElGamal private key, it
+// decrypts ciphertexts in the ASN.1 DER form of [Marshal].
+//
+// The plaintext is encoded in big-endian: 4 bytes for uint32, 4 bytes of
+// two's complement for int32, and 8 bytes for range.
+type Decrypter struct {
+	key DHKey
+	pub crypto.PublicKey
+}
+
+// NewDecrypter returns the [Decrypter] of an SM2 private key.
+func NewDecrypter(priv *sm2.PrivateKey) *Decrypter {
+	return &Decrypter{key: newPrivateKey(priv), pub: priv.Public()}
+}
+
+// NewTwistedDecrypter returns the [Decrypter] of a Twisted private key.
+func NewTwistedDecrypter(priv *TwistedPrivateKey) *Decrypter {
+	return &Decrypter{key: priv, pub: priv.Public()}
+}
+
+// Public returns the public key corresponding to the private key.
+func (d *Decrypter) Public() crypto.PublicKey {
+	return d.pub
+}
+
+// Decrypt decrypts the DER encoded ciphertext msg, opts must be nil, which
+// means uint32, or a *DecrypterOpts. The rand is not used.
+func (d *Decrypter) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	typ, limit, ctx := PlaintextUint32, uint64(0), context.Background()
+	if opts != nil {
+		o, ok := opts.(*DecrypterOpts)
+		if !ok {
+			return nil, ErrInvalidDecrypterOpts
+		}
+		typ, limit = o.Type, o.Limit
+		if o.Context != nil {
+			ctx = o.Context
+		}
+	}
+	ciphertext, err := Unmarshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case PlaintextUint32:
+		v, err := decryptUint32(d.key, ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		return binary.BigEndian.AppendUint32(nil, v), nil
+	case PlaintextInt32:
+		v, err := decryptInt32(d.key, ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		return binary.BigEndian.AppendUint32(nil, uint32(v)), nil
+	case PlaintextRange:
+		if limit == 0 {
+			return nil, ErrInvalidDecrypterOpts
+		}
+		s, err := NewDecryptionSession(d.key, ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		v, err := s.Search(ctx, limit)
+		if err != nil {
+			return nil, err
+		}
+		if v >= limit {
+			return nil, ErrOverflow
+		}
+		return binary.BigEndian.AppendUint64(nil, v), nil
+	}
+	return nil, ErrInvalidDecrypterOpts
+}
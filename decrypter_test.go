@@ -0,0 +1,74 @@
+package sm2elgamal
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func TestDecrypter(t *testing.T) {
+	std, _ := sm2.GenerateKey(rand.Reader)
+	stdDecrypter := NewDecrypter(std)
+	twistedDecrypter := NewTwistedDecrypter(priv)
+	if !std.PublicKey.Equal(stdDecrypter.Public()) || !priv.PublicKey.Equal(twistedDecrypter.Public()) {
+		t.Fatal("public key mismatch")
+	}
+
+	c1, err := EncryptInt32(rand.Reader, &std.PublicKey, -3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := priv.EncryptUint32(rand.Reader, 0xffffffff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c3 := new(Ciphertext).Add(c2, c2)
+
+	cases := []struct {
+		decrypter crypto.Decrypter
+		c         *Ciphertext
+		opts      crypto.DecrypterOpts
+		expected  []byte
+	}{
+		{stdDecrypter, c1, &DecrypterOpts{Type: PlaintextInt32}, binary.BigEndian.AppendUint32(nil, 0xfffffffd)},
+		{twistedDecrypter, c2, nil, binary.BigEndian.AppendUint32(nil, 0xffffffff)},
+		{twistedDecrypter, c3, &DecrypterOpts{Type: PlaintextRange, Limit: 1 << 34}, binary.BigEndian.AppendUint64(nil, 0x1fffffffe)},
+	}
+	for i, c := range cases {
+		der, err := Marshal(c.c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plaintext, err := c.decrypter.Decrypt(nil, der, c.opts)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if string(plaintext) != string(c.expected) {
+			t.Fatalf("case %d: expected %x, got %x", i, c.expected, plaintext)
+		}
+	}
+
+	der, err := Marshal(c3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = twistedDecrypter.Decrypt(nil, der, &DecrypterOpts{Type: PlaintextRange, Limit: 1 << 32}); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+	if _, err = twistedDecrypter.Decrypt(nil, der, crypto.SHA256); err != ErrInvalidDecrypterOpts {
+		t.Fatalf("expected ErrInvalidDecrypterOpts, got %v", err)
+	}
+	if _, err = twistedDecrypter.Decrypt(nil, der, &DecrypterOpts{Type: PlaintextRange}); err != ErrInvalidDecrypterOpts {
+		t.Fatalf("expected ErrInvalidDecrypterOpts, got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = twistedDecrypter.Decrypt(nil, der, &DecrypterOpts{Type: PlaintextRange, Limit: math.MaxUint64, Context: ctx}); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package sm2elgamal
+
+import (
+	"crypto/elliptic"
+	"math/big"
+)
+
+// Decryptor decrypts ciphertexts with configurable search behaviour.
+// The zero value is ready to use and behaves like DecryptUint32/DecryptInt32.
+type Decryptor struct {
+	uniform bool
+	cache   *pointCache
+}
+
+// DecryptorOption configures a Decryptor.
+type DecryptorOption func(*Decryptor)
+
+// WithUniformTiming makes the decryptor always run the full giant-step schedule
+// and do the same number of table lookups whatever the plaintext is, so that
+// the decryption time does not reveal the magnitude or the sign of the value.
+//
+// The cost is that every decryption is as slow as the worst case:
+// 2^11 point additions and lookups for uint32, and one extra scalar
+// multiplication plus 2 * 2^10 point additions and lookups for int32.
+func WithUniformTiming() DecryptorOption {
+	return func(d *Decryptor) {
+		d.uniform = true
+	}
+}
+
+// WithCache keeps the last size recovered points m*G and their values in a
+// LRU cache, so decrypting a ciphertext whose plaintext was recently seen
+// skips the giant-step search. A cache hit is fast, so the decryption time
+// reveals whether the value was recently decrypted even with WithUniformTiming.
+func WithCache(size int) DecryptorOption {
+	return func(d *Decryptor) {
+		if size > 0 {
+			d.cache = newPointCache(size)
+		}
+	}
+}
+
+// NewDecryptor creates a Decryptor with the given options.
+func NewDecryptor(opts ...DecryptorOption) *Decryptor {
+	d := &Decryptor{}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// CacheStats returns the statistics of the decryption cache, it is zero
+// if the decryptor was not created with WithCache.
+func (d *Decryptor) CacheStats() CacheStats {
+	if d.cache == nil {
+		return CacheStats{}
+	}
+	return d.cache.stats()
+}
+
+// DecryptUint32 decrypts ciphertext to uint32, if the value overflow, it returns ErrOverflow.
+func (d *Decryptor) DecryptUint32(priv DHKey, ciphertext *Ciphertext) (uint32, error) {
+	if err := checkPlaintextType(ciphertext, PlaintextUint32); err != nil {
+		return 0, err
+	}
+	curve := priv.GetCurve()
+	x, y, err := messagePoint(priv, ciphertext)
+	if err != nil {
+		return 0, err
+	}
+	// the cached values and the uniform search must respect the bound too
+	r := ciphertext.bound.intersect(uint32Range)
+	if r == nil {
+		return 0, ErrOverflow
+	}
+	var key string
+	if d.cache != nil {
+		key = string(elliptic.MarshalCompressed(curve, x, y))
+		if v, ok := d.cache.get(key, r.lo.Int64(), r.hi.Int64()); ok {
+			return uint32(v), nil
+		}
+	}
+	var value uint32
+	if d.uniform {
+		if value, err = searchUint32Uniform(curve, x, y); err == nil && !r.contains(big.NewInt(int64(value))) {
+			value, err = 0, ErrOverflow
+		}
+	} else if ciphertext.bound != nil {
+		value, err = searchUint32Bounded(curve, x, y, ciphertext.bound)
+	} else {
+		value, err = searchUint32(curve, x, y)
+	}
+	if err == nil && d.cache != nil {
+		d.cache.put(key, int64(value))
+	}
+	return value, err
+}
+
+// DecryptInt32 decrypts ciphertext to int32, if the value overflow, it returns ErrOverflow.
+func (d *Decryptor) DecryptInt32(priv DHKey, ciphertext *Ciphertext) (int32, error) {
+	if err := checkPlaintextType(ciphertext, PlaintextInt32); err != nil {
+		return 0, err
+	}
+	curve := priv.GetCurve()
+	x, y, err := messagePoint(priv, ciphertext)
+	if err != nil {
+		return 0, err
+	}
+	// the cached values and the uniform search must respect the bound too
+	r := ciphertext.bound.intersect(int32Range)
+	if r == nil {
+		return 0, ErrOverflow
+	}
+	var key string
+	if d.cache != nil {
+		key = string(elliptic.MarshalCompressed(curve, x, y))
+		if v, ok := d.cache.get(key, r.lo.Int64(), r.hi.Int64()); ok {
+			return int32(v), nil
+		}
+	}
+	var value int32
+	if d.uniform {
+		if value, err = searchInt32Uniform(curve, x, y); err == nil && !r.contains(big.NewInt(int64(value))) {
+			value, err = 0, ErrOverflow
+		}
+	} else if ciphertext.bound != nil {
+		value, err = searchInt32Bounded(curve, x, y, ciphertext.bound)
+	} else {
+		value, err = searchInt32(curve, x, y)
+	}
+	if err == nil && d.cache != nil {
+		d.cache.put(key, int64(value))
+	}
+	return value, err
+}
+
+// searchUint32Uniform is the constant schedule version of searchUint32,
+// it never returns early.
+func searchUint32Uniform(curve elliptic.Curve, x22, y22 *big.Int) (uint32, error) {
+	table, err := lookupTable()
+	if err != nil {
+		return 0, err
+	}
+	value, found := searchUniform(curve, table, x22, y22, giantSteps)
+	if !found {
+		return 0, ErrOverflow
+	}
+	return uint32(value), nil
+}
+
+// searchInt32Uniform is the constant schedule version of searchInt32,
+// both the positive and the negative half are always searched.
+func searchInt32Uniform(curve elliptic.Curve, x22, y22 *big.Int) (int32, error) {
+	table, err := lookupTable()
+	if err != nil {
+		return 0, err
+	}
+	xNeg, yNeg := curve.ScalarMult(x22, y22, nMinusOne.Bytes())
+
+	pos, posFound := searchUniform(curve, table, x22, y22, signedGiantSteps)
+	neg, negFound := searchUniform(curve, table, xNeg, yNeg, signedGiantSteps)
+	switch {
+	case posFound:
+		return int32(pos), nil
+	case negFound:
+		return -int32(neg), nil
+	}
+	return 0, ErrOverflow
+}
+
+// searchUniform finds m in [0, steps * babySteps) with (x, y) = mG. It always
+// runs all the giant steps and does one table lookup per step.
+func searchUniform(curve elliptic.Curve, table map[string]uint32, x, y *big.Int, steps int) (uint64, bool) {
+	var (
+		value uint64
+		found bool
+	)
+	for i := 0; i < steps; i++ {
+		if i > 0 {
+			x, y = curve.Add(x, y, giantBaseX, giantBaseY)
+		}
+		isZero := x.Sign() == 0 && y.Sign() == 0
+		c := elliptic.MarshalCompressed(curve, x, y)
+		v, prs := table[string(c[:poinCompressionLen])]
+		if !found && (isZero || prs) {
+			value = uint64(i)*uint64(babySteps) + uint64(v)
+			found = true
+		}
+	}
+	return value, found
+}
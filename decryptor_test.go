@@ -0,0 +1,141 @@
+package sm2elgamal
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func TestUniformDecryptUint32(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	d := NewDecryptor(WithUniformTiming())
+	for _, m := range []uint32{0, 1, uint32(babySteps) - 1, uint32(babySteps), uint32(3*babySteps + 5), 0xffffffff} {
+		ciphertext, err := EncryptUint32(rand.Reader, &priv.PublicKey, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := d.DecryptUint32(NewPrivateKey(priv), ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != m {
+			t.Fatalf("expected %x, got %x", m, v)
+		}
+	}
+	ciphertext, err := EncryptInt32(rand.Reader, &priv.PublicKey, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.DecryptUint32(NewPrivateKey(priv), ciphertext)
+	if err != ErrPlaintextTypeMismatch {
+		t.Fatal("should be plaintext type mismatch error")
+	}
+	// an untyped -1 is out of uint32 range
+	ciphertext, _, err = EncryptReturningOpening(rand.Reader, &priv.PublicKey, big.NewInt(-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.DecryptUint32(NewPrivateKey(priv), ciphertext)
+	if err != ErrOverflow {
+		t.Fatal("should be overflow error")
+	}
+}
+
+func TestUniformDecryptInt32(t *testing.T) {
+	d := NewDecryptor(WithUniformTiming())
+	for _, m := range []int32{0, 1, -1, int32(babySteps), -int32(babySteps), 0x7fffffff, -0x7fffffff} {
+		ciphertext, err := priv.EncryptInt32(rand.Reader, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := d.DecryptInt32(priv, ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != m {
+			t.Fatalf("expected %x, got %x", m, v)
+		}
+	}
+}
+
+func TestDecryptorCache(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	key := NewPrivateKey(priv)
+	d := NewDecryptor(WithCache(2))
+	decrypt := func(m uint32) {
+		t.Helper()
+		ciphertext, err := EncryptUint32(rand.Reader, &priv.PublicKey, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := d.DecryptUint32(key, ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != m {
+			t.Fatalf("expected %x, got %x", m, v)
+		}
+	}
+	decrypt(0xfffffff0)
+	decrypt(0xfffffff0)
+	decrypt(1)
+	decrypt(2) // evicts 0xfffffff0
+	decrypt(0xfffffff0)
+	stats := d.CacheStats()
+	if stats.Hits != 1 || stats.Misses != 4 || stats.Len != 2 {
+		t.Fatalf("unexpected cache stats %+v", stats)
+	}
+
+	// the cached value of 0xfffffff0 is out of int32 range
+	ciphertext, err := EncryptUint32(rand.Reader, &priv.PublicKey, 0xfffffff0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = d.DecryptInt32(key, ciphertext); err != ErrPlaintextTypeMismatch {
+		t.Fatal("should be plaintext type mismatch error")
+	}
+	ciphertext, _, err = EncryptReturningOpening(rand.Reader, &priv.PublicKey, big.NewInt(0xfffffff0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = d.DecryptInt32(key, ciphertext); err != ErrOverflow {
+		t.Fatal("should be overflow error")
+	}
+}
+
+func TestDecryptorCacheBound(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	key := NewPrivateKey(priv)
+	for _, d := range []*Decryptor{NewDecryptor(WithCache(2)), NewDecryptor(WithCache(2), WithUniformTiming())} {
+		ciphertext, err := EncryptUint32(rand.Reader, &priv.PublicKey, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, err := d.DecryptUint32(key, ciphertext); err != nil || v != 100 {
+			t.Fatalf("expected 100, got %v, %v", v, err)
+		}
+		// the cached value is out of the bound
+		bounded, err := WithBound(ciphertext, big.NewInt(0), big.NewInt(10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = d.DecryptUint32(key, bounded); err != ErrOverflow {
+			t.Fatalf("expected ErrOverflow, got %v", err)
+		}
+		ciphertext, err = EncryptInt32(rand.Reader, &priv.PublicKey, -100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, err := d.DecryptInt32(key, ciphertext); err != nil || v != -100 {
+			t.Fatalf("expected -100, got %v, %v", v, err)
+		}
+		if bounded, err = WithBound(ciphertext, big.NewInt(-10), big.NewInt(10)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = d.DecryptInt32(key, bounded); err != ErrOverflow {
+			t.Fatalf("expected ErrOverflow, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package sm2elgamal
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+// errInvalidDHResult is returned when a DHKey returns a point not on the curve.
+var errInvalidDHResult = errors.New("invalid DH result")
+
+// DHKey is the private key abstraction of decryption. It only has to compute
+// d*P for a given point P, so the private value d can be kept in a separate
+// process, a secure enclave or a key management service, and never be
+// revealed to the application.
+type DHKey interface {
+	// GetCurve returns this private key's Curve
+	GetCurve() elliptic.Curve
+	// DH returns d*(x, y), (0, 0) is the point at infinity.
+	DH(x, y *big.Int) (*big.Int, *big.Int, error)
+}
+
+// privateKeyDH adapts a [PrivateKey] to [DHKey].
+type privateKeyDH struct {
+	PrivateKey
+}
+
+func (priv privateKeyDH) DH(x, y *big.Int) (*big.Int, *big.Int, error) {
+	dx, dy := priv.GetCurve().ScalarMult(x, y, priv.GetD().Bytes())
+	return dx, dy, nil
+}
+
+// DHKeyFromPrivateKey returns the [DHKey] of a [PrivateKey].
+func DHKeyFromPrivateKey(priv PrivateKey) DHKey {
+	if k, ok := priv.(DHKey); ok {
+		return k
+	}
+	return privateKeyDH{priv}
+}
+
+// NewPrivateKey wraps an SM2 private key to implement the [DHKey] interface,
+// the result implements the [PrivateKey] interface too.
+func NewPrivateKey(k *sm2.PrivateKey) DHKey {
+	return newPrivateKey(k)
+}
+
+func (priv *sm2PrivateKey) DH(x, y *big.Int) (*big.Int, *big.Int, error) {
+	dx, dy := priv.Curve.ScalarMult(x, y, priv.D.Bytes())
+	return dx, dy, nil
+}
+
+// DecryptUint32DH decrypts ciphertext to uint32 with a DH key, if the value overflow, it returns ErrOverflow.
+func DecryptUint32DH(key DHKey, ciphertext *Ciphertext) (uint32, error) {
+	return decryptUint32(key, ciphertext)
+}
+
+// DecryptInt32DH decrypts ciphertext to int32 with a DH key, if the value overflow, it returns ErrOverflow.
+// The negative value will be slower than positive value.
+func DecryptInt32DH(key DHKey, ciphertext *Ciphertext) (int32, error) {
+	return decryptInt32(key, ciphertext)
+}
@@ -0,0 +1,110 @@
+package sm2elgamal
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+type dhRequest struct {
+	x, y  *big.Int
+	reply chan [2]*big.Int
+}
+
+// oracleKey is a DHKey whose private value lives in another goroutine only.
+type oracleKey struct {
+	curve    elliptic.Curve
+	requests chan dhRequest
+}
+
+func newOracleKey(priv *sm2.PrivateKey) *oracleKey {
+	k := &oracleKey{curve: priv.Curve, requests: make(chan dhRequest)}
+	d := new(big.Int).Set(priv.D)
+	go func() {
+		for req := range k.requests {
+			x, y := k.curve.ScalarMult(req.x, req.y, d.Bytes())
+			req.reply <- [2]*big.Int{x, y}
+		}
+	}()
+	return k
+}
+
+func (k *oracleKey) GetCurve() elliptic.Curve {
+	return k.curve
+}
+
+func (k *oracleKey) DH(x, y *big.Int) (*big.Int, *big.Int, error) {
+	reply := make(chan [2]*big.Int)
+	k.requests <- dhRequest{x, y, reply}
+	p := <-reply
+	return p[0], p[1], nil
+}
+
+type failingKey struct {
+	oracleKey
+}
+
+func (k *failingKey) DH(x, y *big.Int) (*big.Int, *big.Int, error) {
+	return nil, nil, errors.New("key service is unavailable")
+}
+
+type badKey struct {
+	oracleKey
+}
+
+func (k *badKey) DH(x, y *big.Int) (*big.Int, *big.Int, error) {
+	return big.NewInt(1), big.NewInt(1), nil
+}
+
+func TestDecryptDH(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	key := newOracleKey(priv)
+	defer close(key.requests)
+	for _, m := range []int32{0, 5, -5, int32(babySteps) + 1} {
+		ciphertext, err := EncryptInt32(rand.Reader, &priv.PublicKey, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := DecryptInt32DH(key, ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != m {
+			t.Fatalf("expected %d, got %d", m, v)
+		}
+	}
+	ciphertext, err := EncryptUint32(rand.Reader, &priv.PublicKey, 0xfffffff0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := DecryptUint32DH(key, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 0xfffffff0 {
+		t.Fatalf("expected %x, got %x", 0xfffffff0, v)
+	}
+	v, err = DecryptUint32DH(DHKeyFromPrivateKey(plainPrivateKey{newPrivateKey(priv)}), ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 0xfffffff0 {
+		t.Fatalf("expected %x, got %x", 0xfffffff0, v)
+	}
+
+	if _, err = DecryptUint32DH(&failingKey{*key}, ciphertext); err == nil {
+		t.Fatal("should fail")
+	}
+	if _, err = DecryptUint32DH(&badKey{*key}, ciphertext); err != errInvalidDHResult {
+		t.Fatalf("expected errInvalidDHResult, got %v", err)
+	}
+}
+
+// plainPrivateKey hides the DH method of the wrapped key.
+type plainPrivateKey struct {
+	PrivateKey
+}
@@ -3,13 +3,10 @@ package sm2elgamal
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"math/big"
-	"os"
-	"sync"
 
 	"github.com/emmansun/gmsm/sm2"
 	"golang.org/x/crypto/cryptobyte"
@@ -24,142 +21,101 @@ var (
 )
 
 var (
-	nMinusOne              *big.Int
-	giantBaseX, giantBaseY *big.Int
-	babyLookupTable        map[string]uint32
-	babyLookupTableOnce    sync.Once
+	nMinusOne              = new(big.Int).Sub(sm2.P256().Params().N, big.NewInt(1))
+	giantBaseX, giantBaseY = sm2.P256().ScalarBaseMult(new(big.Int).Sub(sm2.P256().Params().N, big.NewInt(int64(babySteps))).Bytes())
 )
 
 var ErrOverflow = fmt.Errorf("the value is overflow")
 
-func lookupTable() map[string]uint32 {
-	babyLookupTableOnce.Do(func() {
-		sm2Curve := sm2.P256()
-		nMinusOne = new(big.Int).Sub(sm2.P256().Params().N, big.NewInt(1))
-		giantBaseX, giantBaseY = sm2Curve.ScalarBaseMult(new(big.Int).Sub(sm2.P256().Params().N, big.NewInt(int64(babySteps))).Bytes())
-
-		babyLookupTable = make(map[string]uint32)
-		bin, err := os.ReadFile("sm2_lookup_table.bin")
-		if err != nil {
-			panic(err)
-		}
-		size := len(bin) / poinCompressionLen
-
-		for i := 0; i < size; i++ {
-			p := bin[:poinCompressionLen]
-			bin = bin[poinCompressionLen:]
-			babyLookupTable[string(p)] = uint32(i + 1)
-		}
-	})
-	return babyLookupTable
-}
-
 // Ciphertext sturcture represents EL-Gamal ecnryption result.
 type Ciphertext struct {
 	curve elliptic.Curve
 	c1    []byte
 	c2    []byte
+	tags
+	bound *interval // public bound of the plaintext, nil if unknown
 }
 
 // Add returns c1 + c2.
+// It panics if c1 or c2 is invalid or they are of different schemes or keys,
+// use the [Add] function for untrusted input.
 func (ret *Ciphertext) Add(c1, c2 *Ciphertext) *Ciphertext {
-	x11, y11 := elliptic.UnmarshalCompressed(c1.curve, c1.c1)
-	x12, y12 := elliptic.UnmarshalCompressed(c1.curve, c1.c2)
-
-	x21, y21 := elliptic.UnmarshalCompressed(c2.curve, c2.c1)
-	x22, y22 := elliptic.UnmarshalCompressed(c2.curve, c2.c2)
-
-	x31, y31 := c1.curve.Add(x11, y11, x21, y21)
-	x32, y32 := c1.curve.Add(x12, y12, x22, y22)
-
-	ret.curve = c1.curve
-	ret.c1 = elliptic.MarshalCompressed(c1.curve, x31, y31)
-	ret.c2 = elliptic.MarshalCompressed(c1.curve, x32, y32)
-	return ret
+	return ret.apply(func(v []*Ciphertext) (*Ciphertext, error) {
+		return Add(v[0], v[1])
+	}, c1, c2)
 }
 
-// Sum returns cumulative sum value
+// Sum returns cumulative sum value.
+// It panics if there is no or invalid value, or the values are of different
+// schemes or keys, use the [Sum] function for untrusted input.
 func (ret *Ciphertext) Sum(values ...*Ciphertext) *Ciphertext {
-	v0 := values[0]
-	v0c1x, v0c1y := elliptic.UnmarshalCompressed(v0.curve, v0.c1)
-	v0c2x, v0c2y := elliptic.UnmarshalCompressed(v0.curve, v0.c2)
-	for i := 1; i < len(values); i++ {
-		vi := values[i]
-		vic1x, vic1y := elliptic.UnmarshalCompressed(vi.curve, vi.c1)
-		vic2x, vic2y := elliptic.UnmarshalCompressed(vi.curve, vi.c2)
-		v0c1x, v0c1y = v0.curve.Add(vic1x, vic1y, v0c1x, v0c1y)
-		v0c2x, v0c2y = v0.curve.Add(vic2x, vic2y, v0c2x, v0c2y)
-	}
-	ret.curve = v0.curve
-	ret.c1 = elliptic.MarshalCompressed(v0.curve, v0c1x, v0c1y)
-	ret.c2 = elliptic.MarshalCompressed(v0.curve, v0c2x, v0c2y)
-	return ret
+	return ret.apply(func(v []*Ciphertext) (*Ciphertext, error) {
+		return Sum(v...)
+	}, values...)
 }
 
 // Sub returns c1 - c2.
+// It panics if c1 or c2 is invalid or they are of different schemes or keys,
+// use the [Sub] function for untrusted input.
 func (ret *Ciphertext) Sub(c1, c2 *Ciphertext) *Ciphertext {
-	x11, y11 := elliptic.UnmarshalCompressed(c1.curve, c1.c1)
-	x12, y12 := elliptic.UnmarshalCompressed(c1.curve, c1.c2)
-
-	x21, y21 := elliptic.UnmarshalCompressed(c2.curve, c2.c1)
-	x22, y22 := elliptic.UnmarshalCompressed(c2.curve, c2.c2)
-
-	nMinus1 := new(big.Int).Sub(c1.curve.Params().N, big.NewInt(1)).Bytes()
-
-	x21, y21 = c1.curve.ScalarMult(x21, y21, nMinus1)
-	x22, y22 = c1.curve.ScalarMult(x22, y22, nMinus1)
-
-	x31, y31 := c1.curve.Add(x11, y11, x21, y21)
-	x32, y32 := c1.curve.Add(x12, y12, x22, y22)
-
-	ret.curve = c1.curve
-	ret.c1 = elliptic.MarshalCompressed(c1.curve, x31, y31)
-	ret.c2 = elliptic.MarshalCompressed(c1.curve, x32, y32)
-	return ret
+	return ret.apply(func(v []*Ciphertext) (*Ciphertext, error) {
+		return Sub(v[0], v[1])
+	}, c1, c2)
 }
 
 // ScalarMultUint32 scalar mutiples the ciphertext with m.
+// It panics if c is invalid, use the [ScalarMultUint32] function for untrusted input.
 func (ret *Ciphertext) ScalarMultUint32(c *Ciphertext, m uint32) *Ciphertext {
-	if m == 0 {
-		panic("can't scalar multiple zero")
-	}
-	x1, y1 := elliptic.UnmarshalCompressed(c.curve, c.c1)
-	x2, y2 := elliptic.UnmarshalCompressed(c.curve, c.c2)
-
-	x1, y1 = c.curve.ScalarMult(x1, y1, big.NewInt(int64(m)).Bytes())
-	x2, y2 = c.curve.ScalarMult(x2, y2, big.NewInt(int64(m)).Bytes())
-	ret.c1 = elliptic.MarshalCompressed(c.curve, x1, y1)
-	ret.c2 = elliptic.MarshalCompressed(c.curve, x2, y2)
-
-	ret.curve = c.curve
-
-	return ret
+	return ret.apply(func(v []*Ciphertext) (*Ciphertext, error) {
+		return ScalarMultUint32(v[0], m)
+	}, c)
 }
 
 // ScalarMultInt32 scalar mutiples the ciphertext with m.
+// It panics if c is invalid, use the [ScalarMultInt32] function for untrusted input.
 func (ret *Ciphertext) ScalarMultInt32(c *Ciphertext, m int32) *Ciphertext {
-	if m == 0 {
-		panic("can't scalar multiple zero")
-	}
-	x1, y1 := elliptic.UnmarshalCompressed(c.curve, c.c1)
-	x2, y2 := elliptic.UnmarshalCompressed(c.curve, c.c2)
-	mValue := getFieldValue(c.curve, m)
-	x1, y1 = c.curve.ScalarMult(x1, y1, mValue.Bytes())
-	x2, y2 = c.curve.ScalarMult(x2, y2, mValue.Bytes())
-	ret.c1 = elliptic.MarshalCompressed(c.curve, x1, y1)
-	ret.c2 = elliptic.MarshalCompressed(c.curve, x2, y2)
-
-	ret.curve = c.curve
-
-	return ret
+	return ret.apply(func(v []*Ciphertext) (*Ciphertext, error) {
+		return ScalarMultInt32(v[0], m)
+	}, c)
 }
 
+// Tags of the optional fields of the ciphertext in ASN.1 DER form.
+var (
+	schemeTag         = asn1.Tag(0).Constructed().ContextSpecific()
+	plaintextTypeTag  = asn1.Tag(1).Constructed().ContextSpecific()
+	keyFingerprintTag = asn1.Tag(2).Constructed().ContextSpecific()
+)
+
 // Marshal converts the ciphertext to ASN.1 DER form.
+//
+//	Ciphertext ::= SEQUENCE {
+//	  c1             OCTET STRING,
+//	  c2             OCTET STRING,
+//	  scheme         [0] INTEGER OPTIONAL,
+//	  plaintextType  [1] INTEGER OPTIONAL,
+//	  keyFingerprint [2] OCTET STRING OPTIONAL }
+//
+// The optional fields are omitted if unknown.
 func Marshal(c *Ciphertext) ([]byte, error) {
 	var b cryptobyte.Builder
 	b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
 		b.AddASN1OctetString(c.c1)
 		b.AddASN1OctetString(c.c2)
+		if c.scheme != 0 {
+			b.AddASN1(schemeTag, func(b *cryptobyte.Builder) {
+				b.AddASN1Uint64(uint64(c.scheme))
+			})
+		}
+		if c.ptype != 0 {
+			b.AddASN1(plaintextTypeTag, func(b *cryptobyte.Builder) {
+				b.AddASN1Uint64(uint64(c.ptype))
+			})
+		}
+		if c.key != nil {
+			b.AddASN1(keyFingerprintTag, func(b *cryptobyte.Builder) {
+				b.AddASN1OctetString(c.key)
+			})
+		}
 	})
 	return b.Bytes()
 }
@@ -167,18 +123,34 @@ func Marshal(c *Ciphertext) ([]byte, error) {
 // Unmarshal parses ciphertext in ASN.1 DER form.
 func Unmarshal(der []byte) (*Ciphertext, error) {
 	var (
-		ret   *Ciphertext = &Ciphertext{}
-		inner cryptobyte.String
+		ret           *Ciphertext = &Ciphertext{}
+		inner         cryptobyte.String
+		scheme, ptype int
+		key           []byte
+		hasKey        bool
 	)
 	input := cryptobyte.String(der)
 	if !input.ReadASN1(&inner, asn1.SEQUENCE) ||
 		!input.Empty() ||
 		!inner.ReadASN1Bytes(&ret.c1, asn1.OCTET_STRING) ||
 		!inner.ReadASN1Bytes(&ret.c2, asn1.OCTET_STRING) ||
-		!inner.Empty() {
+		!inner.ReadOptionalASN1Integer(&scheme, schemeTag, 0) ||
+		!inner.ReadOptionalASN1Integer(&ptype, plaintextTypeTag, 0) ||
+		!inner.ReadOptionalASN1OctetString(&key, &hasKey, keyFingerprintTag) ||
+		!inner.Empty() ||
+		scheme < 0 || scheme > int(SchemeTwisted) ||
+		ptype < 0 || ptype > int(PlaintextRange) ||
+		hasKey && len(key) != keyFingerprintLen {
 		return nil, errors.New("invalid asn1 format ciphertext")
 	}
+	ret.scheme, ret.ptype = SchemeID(scheme), PlaintextType(ptype)
+	if hasKey {
+		ret.key = key
+	}
 	ret.curve = sm2.P256()
+	if _, _, _, _, err := ret.points(); err != nil {
+		return nil, err
+	}
 	return ret, nil
 }
 
@@ -210,24 +182,9 @@ func EncryptUint32(random io.Reader, pub *ecdsa.PublicKey, m uint32) (*Ciphertex
 	if err != nil {
 		return nil, err
 	}
-	// c1 = rG
-	x1, y1 := pub.Curve.ScalarBaseMult(r.Bytes())
-	// c2 = rP
-	x11, y11 := pub.Curve.ScalarMult(pub.X, pub.Y, r.Bytes())
-
-	var x2, y2 *big.Int
-	if m == 0 {
-		x2 = big.NewInt(0)
-		y2 = big.NewInt(0)
-	} else {
-		var a [4]byte
-		binary.BigEndian.PutUint32(a[:], m)
-		x2, y2 = pub.Curve.ScalarBaseMult(a[:])
-	}
-	// c2 = rP + mG
-	x2, y2 = pub.Curve.Add(x11, y11, x2, y2)
-
-	return &Ciphertext{pub.Curve, elliptic.MarshalCompressed(pub.Curve, x1, y1), elliptic.MarshalCompressed(pub.Curve, x2, y2)}, nil
+	ret := encrypt(pub, new(big.Int).SetUint64(uint64(m)), r)
+	ret.ptype = PlaintextUint32
+	return ret, nil
 }
 
 func getFieldValue(curve elliptic.Curve, m int32) *big.Int {
@@ -244,24 +201,45 @@ func EncryptInt32(random io.Reader, pub *ecdsa.PublicKey, m int32) (*Ciphertext,
 	if err != nil {
 		return nil, err
 	}
+	ret := encrypt(pub, getFieldValue(pub.Curve, m), r)
+	ret.ptype = PlaintextInt32
+	return ret, nil
+}
+
+// encrypt returns (rG, rP + mG), m must be in [0, N).
+func encrypt(pub *ecdsa.PublicKey, m, r *big.Int) *Ciphertext {
 	// c1 = rG
 	x1, y1 := pub.Curve.ScalarBaseMult(r.Bytes())
 	// c2 = rP
 	x11, y11 := pub.Curve.ScalarMult(pub.X, pub.Y, r.Bytes())
 
 	var x2, y2 *big.Int
-	if m == 0 {
+	if m.Sign() == 0 {
 		x2 = big.NewInt(0)
 		y2 = big.NewInt(0)
 	} else {
-		mVal := getFieldValue(pub.Curve, m)
-		x2, y2 = pub.Curve.ScalarBaseMult(mVal.Bytes())
+		x2, y2 = pub.Curve.ScalarBaseMult(m.Bytes())
 	}
 	// c2 = rP + mG = r*dG + mG = d*rG + mG = d*c1 + mG
 	// mG = c2 - d*c1
 	x2, y2 = pub.Curve.Add(x11, y11, x2, y2)
 
-	return &Ciphertext{pub.Curve, elliptic.MarshalCompressed(pub.Curve, x1, y1), elliptic.MarshalCompressed(pub.Curve, x2, y2)}, nil
+	ret := newCiphertext(pub.Curve, x1, y1, x2, y2)
+	ret.scheme, ret.key = SchemeStandard, PublicKeyFingerprint(pub)
+	return ret
+}
+
+// Rerandomize returns a fresh ciphertext of the same plaintext by adding an
+// encryption of zero to c, that is (c1 + r'G, c2 + r'P) with a new random r'.
+// The result can't be linked to c by anyone without the private key.
+func Rerandomize(random io.Reader, pub *ecdsa.PublicKey, c *Ciphertext) (*Ciphertext, error) {
+	r, err := randFieldElement(pub.Curve, random)
+	if err != nil {
+		return nil, err
+	}
+	zero := encrypt(pub, new(big.Int), r)
+	zero.bound = newInterval(0, 0)
+	return Add(c, zero)
 }
 
 // PrivateKey is an interface for elgamal decription requirement abstraction
@@ -300,19 +278,61 @@ func DecryptUint32(priv *sm2.PrivateKey, ciphertext *Ciphertext) (uint32, error)
 }
 
 // decryptUint32 decrypts ciphertext to uint32, if the value overflow, it returns ErrOverflow.
-func decryptUint32(priv PrivateKey, ciphertext *Ciphertext) (uint32, error) {
+func decryptUint32(priv DHKey, ciphertext *Ciphertext) (uint32, error) {
+	if err := checkPlaintextType(ciphertext, PlaintextUint32); err != nil {
+		return 0, err
+	}
+	x22, y22, err := messagePoint(priv, ciphertext)
+	if err != nil {
+		return 0, err
+	}
+	if ciphertext.bound != nil {
+		return searchUint32Bounded(priv.GetCurve(), x22, y22, ciphertext.bound)
+	}
+	return searchUint32(priv.GetCurve(), x22, y22)
+}
+
+// messagePoint returns mG = c2 - d*c1.
+func messagePoint(priv DHKey, ciphertext *Ciphertext) (*big.Int, *big.Int, error) {
 	curve := priv.GetCurve()
-	x1, y1 := elliptic.UnmarshalCompressed(curve, ciphertext.c1)
-	x2, y2 := elliptic.UnmarshalCompressed(curve, ciphertext.c2)
+	x1, y1, x2, y2, err := ciphertext.points()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !sameCurve(curve, ciphertext.curve) {
+		return nil, nil, ErrCurveMismatch
+	}
+	if err := checkKey(priv, ciphertext); err != nil {
+		return nil, nil, err
+	}
 
-	x11, y11 := curve.ScalarMult(x1, y1, new(big.Int).Sub(curve.Params().N, priv.GetD()).Bytes())
+	x11, y11, err := priv.DH(x1, y1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if x11.Sign() != 0 || y11.Sign() != 0 {
+		if !curve.IsOnCurve(x11, y11) {
+			return nil, nil, errInvalidDHResult
+		}
+		// -d*c1
+		y11 = new(big.Int).Sub(curve.Params().P, y11)
+	}
 	x22, y22 := curve.Add(x2, y2, x11, y11)
+	return x22, y22, nil
+}
+
+// searchUint32 finds m in uint32 range with (x22, y22) = mG.
+func searchUint32(curve elliptic.Curve, x22, y22 *big.Int) (uint32, error) {
 	if x22.Sign() == 0 && y22.Sign() == 0 {
 		return 0, nil
 	}
+	table, err := lookupTable()
+	if err != nil {
+		return 0, err
+	}
 
 	c := elliptic.MarshalCompressed(curve, x22, y22)
-	value, prs := lookupTable()[string(c[:poinCompressionLen])]
+	value, prs := table[string(c[:poinCompressionLen])]
 	if prs {
 		return value, nil
 	}
@@ -323,7 +343,7 @@ func decryptUint32(priv PrivateKey, ciphertext *Ciphertext) (uint32, error) {
 			return uint32(i * babySteps), nil
 		}
 		c = elliptic.MarshalCompressed(curve, x22, y22)
-		value, prs = lookupTable()[string(c[:poinCompressionLen])]
+		value, prs = table[string(c[:poinCompressionLen])]
 		if prs {
 			return uint32(i*babySteps) + value, nil
 		}
@@ -339,25 +359,38 @@ func DecryptInt32(priv *sm2.PrivateKey, ciphertext *Ciphertext) (int32, error) {
 
 // decryptInt32 decrypts ciphertext to int32, if the value overflow, it returns ErrOverflow.
 // The negative value will be slower than positive value.
-func decryptInt32(priv PrivateKey, ciphertext *Ciphertext) (int32, error) {
-	curve := priv.GetCurve()
-	x1, y1 := elliptic.UnmarshalCompressed(curve, ciphertext.c1)
-	x2, y2 := elliptic.UnmarshalCompressed(curve, ciphertext.c2)
+func decryptInt32(priv DHKey, ciphertext *Ciphertext) (int32, error) {
+	if err := checkPlaintextType(ciphertext, PlaintextInt32); err != nil {
+		return 0, err
+	}
+	x22, y22, err := messagePoint(priv, ciphertext)
+	if err != nil {
+		return 0, err
+	}
+	if ciphertext.bound != nil {
+		return searchInt32Bounded(priv.GetCurve(), x22, y22, ciphertext.bound)
+	}
+	return searchInt32(priv.GetCurve(), x22, y22)
+}
 
-	x11, y11 := curve.ScalarMult(x1, y1, new(big.Int).Sub(curve.Params().N, priv.GetD()).Bytes())
-	x22, y22 := curve.Add(x2, y2, x11, y11)
+// searchInt32 finds m in int32 range with (x22, y22) = mG.
+func searchInt32(curve elliptic.Curve, x22, y22 *big.Int) (int32, error) {
 	if x22.Sign() == 0 && y22.Sign() == 0 {
 		return 0, nil
 	}
+	table, err := lookupTable()
+	if err != nil {
+		return 0, err
+	}
 
-	ret := decryptSigned(curve, x22, y22)
+	ret := decryptSigned(curve, table, x22, y22)
 	if ret != 0 {
 		return ret, nil
 	}
 
 	xNeg, yNeg := curve.ScalarMult(x22, y22, nMinusOne.Bytes())
 
-	ret = decryptSigned(curve, xNeg, yNeg)
+	ret = decryptSigned(curve, table, xNeg, yNeg)
 	if ret != 0 {
 		return -ret, nil
 	}
@@ -365,9 +398,9 @@ func decryptInt32(priv PrivateKey, ciphertext *Ciphertext) (int32, error) {
 	return 0, ErrOverflow
 }
 
-func decryptSigned(curve elliptic.Curve, x, y *big.Int) int32 {
+func decryptSigned(curve elliptic.Curve, table map[string]uint32, x, y *big.Int) int32 {
 	c := elliptic.MarshalCompressed(curve, x, y)
-	value, prs := lookupTable()[string(c[:poinCompressionLen])]
+	value, prs := table[string(c[:poinCompressionLen])]
 	if prs {
 		return int32(value)
 	}
@@ -377,7 +410,7 @@ func decryptSigned(curve elliptic.Curve, x, y *big.Int) int32 {
 			return int32(i * babySteps)
 		}
 		c := elliptic.MarshalCompressed(curve, x, y)
-		value, prs := lookupTable()[string(c[:poinCompressionLen])]
+		value, prs := table[string(c[:poinCompressionLen])]
 		if prs {
 			return int32(i*babySteps + int(value))
 		}
@@ -404,8 +404,12 @@ func BenchmarkDecryptUint32(b *testing.B) {
 	if err != nil {
 		b.Fatal(err)
 	}
-	if len(lookupTable()) != babySteps-1 {
-		b.Fatalf("lookup table is incorrect, %x", len(lookupTable()))
+	table, err := lookupTable()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if len(table) != babySteps-1 {
+		b.Fatalf("lookup table is incorrect, %x", len(table))
 	}
 	b.ReportAllocs()
 	b.ResetTimer()
@@ -419,3 +423,25 @@ func BenchmarkDecryptUint32(b *testing.B) {
 		}
 	}
 }
+
+func TestRerandomize(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	ciphertext, err := EncryptInt32(rand.Reader, &priv.PublicKey, -10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext2, err := Rerandomize(rand.Reader, &priv.PublicKey, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(ciphertext.c1, ciphertext2.c1) || bytes.Equal(ciphertext.c2, ciphertext2.c2) {
+		t.Fatal("should be different")
+	}
+	v, err := DecryptInt32(priv, ciphertext2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != -10 {
+		t.Fatalf("expected %d, got %d", -10, v)
+	}
+}
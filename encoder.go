@@ -0,0 +1,156 @@
+package sm2elgamal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"time"
+)
+
+// ErrOutOfRange is returned when a value can't be encoded in the range of its [Encoder].
+var ErrOutOfRange = errors.New("value is out of the encoder range")
+
+// Encoder maps the values of a domain type V to plaintext scalars and back.
+type Encoder[V any] interface {
+	// Encode returns the scalar of v.
+	Encode(v V) (int64, error)
+	// Decode returns the value of the scalar m, m is in Range.
+	Decode(m int64) (V, error)
+	// Range returns the valid scalars [min, max], the results of homomorphic
+	// operations must stay in it to be decrypted, and decryption only
+	// searches it, so a small range decrypts fast.
+	Range() (min, max int64)
+}
+
+// EncryptEncoded encodes v with enc and encrypts it with the public key of any scheme.
+func EncryptEncoded[V any](random io.Reader, pub PublicKey, enc Encoder[V], v V) (*Ciphertext, error) {
+	m, err := enc.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	if min, max := enc.Range(); m < min || m > max {
+		return nil, ErrOutOfRange
+	}
+	return pub.Encrypt(random, big.NewInt(m))
+}
+
+// DecryptEncoded decrypts c and decodes the plaintext with enc, it returns
+// ErrOverflow if the plaintext is out of the range of enc.
+func DecryptEncoded[V any](priv DHKey, enc Encoder[V], c *Ciphertext) (V, error) {
+	var zero V
+	x, y, err := messagePoint(priv, c)
+	if err != nil {
+		return zero, err
+	}
+	min, max := enc.Range()
+	if min > max {
+		return zero, ErrOverflow
+	}
+	m, err := searchInterval(context.Background(), priv.GetCurve(), x, y, c.bound.intersect(newInterval(min, max)))
+	if err != nil {
+		return zero, err
+	}
+	return enc.Decode(m.Int64())
+}
+
+// BoolEncoder encodes false as 0 and true as 1.
+type BoolEncoder struct{}
+
+func (BoolEncoder) Encode(v bool) (int64, error) {
+	if v {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (BoolEncoder) Decode(m int64) (bool, error) {
+	return m == 1, nil
+}
+
+func (BoolEncoder) Range() (int64, int64) {
+	return 0, 1
+}
+
+// IntEncoder encodes the integers in [Min, Max] as themselves, it suits
+// counters and the bounded categorical values.
+type IntEncoder struct {
+	Min, Max int64
+}
+
+func (e IntEncoder) Encode(v int64) (int64, error) {
+	if v < e.Min || v > e.Max {
+		return 0, ErrOutOfRange
+	}
+	return v, nil
+}
+
+func (e IntEncoder) Decode(m int64) (int64, error) {
+	return m, nil
+}
+
+func (e IntEncoder) Range() (int64, int64) {
+	return e.Min, e.Max
+}
+
+// EnumEncoder encodes the enum values by their indexes.
+type EnumEncoder[V comparable] struct {
+	values  []V
+	indexes map[V]int64
+}
+
+// NewEnumEncoder creates an [EnumEncoder] of values, the first one is encoded as 0.
+func NewEnumEncoder[V comparable](values ...V) *EnumEncoder[V] {
+	e := &EnumEncoder[V]{values: values, indexes: make(map[V]int64, len(values))}
+	for i, v := range values {
+		if _, ok := e.indexes[v]; !ok {
+			e.indexes[v] = int64(i)
+		}
+	}
+	return e
+}
+
+func (e *EnumEncoder[V]) Encode(v V) (int64, error) {
+	i, ok := e.indexes[v]
+	if !ok {
+		return 0, ErrOutOfRange
+	}
+	return i, nil
+}
+
+func (e *EnumEncoder[V]) Decode(m int64) (V, error) {
+	if m < 0 || m >= int64(len(e.values)) {
+		var zero V
+		return zero, ErrOutOfRange
+	}
+	return e.values[m], nil
+}
+
+func (e *EnumEncoder[V]) Range() (int64, int64) {
+	return 0, int64(len(e.values)) - 1
+}
+
+// DurationEncoder encodes the durations in [Min, Max] as the number of Unit,
+// the durations must be multiples of Unit.
+type DurationEncoder struct {
+	Unit     time.Duration
+	Min, Max time.Duration
+}
+
+func (e DurationEncoder) Encode(v time.Duration) (int64, error) {
+	if e.Unit <= 0 || v%e.Unit != 0 || v < e.Min || v > e.Max {
+		return 0, ErrOutOfRange
+	}
+	return int64(v / e.Unit), nil
+}
+
+func (e DurationEncoder) Decode(m int64) (time.Duration, error) {
+	return time.Duration(m) * e.Unit, nil
+}
+
+func (e DurationEncoder) Range() (int64, int64) {
+	if e.Unit <= 0 {
+		return 0, -1
+	}
+	return int64(e.Min / e.Unit), int64(e.Max / e.Unit)
+}
@@ -0,0 +1,77 @@
+package sm2elgamal
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+)
+
+func testEncoder[V comparable](t *testing.T, sk SecretKey, enc Encoder[V], values ...V) {
+	t.Helper()
+	for _, v := range values {
+		c, err := EncryptEncoded(rand.Reader, sk.Public(), enc, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DecryptEncoded(sk, enc, c)
+		if err != nil {
+			t.Fatalf("%v: %v", v, err)
+		}
+		if got != v {
+			t.Fatalf("expected %v, got %v", v, got)
+		}
+	}
+}
+
+type color string
+
+func TestEncoders(t *testing.T) {
+	sk := NewTwistedSecretKey(priv)
+	testEncoder[bool](t, sk, BoolEncoder{}, false, true)
+	testEncoder[int64](t, sk, IntEncoder{Min: -100, Max: 1 << 40}, -100, 0, 1<<33)
+	testEncoder[color](t, sk, NewEnumEncoder[color]("red", "green", "blue"), "red", "blue")
+	testEncoder[time.Duration](t, sk, DurationEncoder{Unit: time.Second, Min: -time.Hour, Max: 24 * time.Hour}, -time.Hour, 0, 90*time.Minute)
+}
+
+func TestEncoderRange(t *testing.T) {
+	sk := NewTwistedSecretKey(priv)
+	durations := DurationEncoder{Unit: time.Second, Max: time.Hour}
+	if _, err := EncryptEncoded(rand.Reader, sk.Public(), durations, time.Millisecond); err != ErrOutOfRange {
+		t.Fatalf("expected ErrOutOfRange, got %v", err)
+	}
+	if _, err := EncryptEncoded(rand.Reader, sk.Public(), durations, 2*time.Hour); err != ErrOutOfRange {
+		t.Fatalf("expected ErrOutOfRange, got %v", err)
+	}
+	colors := NewEnumEncoder[color]("red", "green")
+	if _, err := EncryptEncoded(rand.Reader, sk.Public(), colors, "blue"); err != ErrOutOfRange {
+		t.Fatalf("expected ErrOutOfRange, got %v", err)
+	}
+
+	// the sum of two true values is out of range of BoolEncoder
+	c1, _ := EncryptEncoded(rand.Reader, sk.Public(), BoolEncoder{}, true)
+	c2, _ := EncryptEncoded(rand.Reader, sk.Public(), BoolEncoder{}, true)
+	sum, err := Add(c1, c2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = DecryptEncoded(sk, BoolEncoder{}, sum); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+	if v, err := DecryptEncoded(sk, IntEncoder{Min: 0, Max: 10}, sum); err != nil || v != 2 {
+		t.Fatalf("expected 2, got %v, %v", v, err)
+	}
+	// below the minimum
+	if _, err = DecryptEncoded(sk, IntEncoder{Min: 3, Max: 10}, sum); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+	diff, err := Sub(c1, sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = DecryptEncoded(sk, IntEncoder{Min: 0, Max: 10}, diff); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+	if v, err := DecryptEncoded(sk, IntEncoder{Min: -10, Max: -1}, diff); err != nil || v != -1 {
+		t.Fatalf("expected -1, got %v, %v", v, err)
+	}
+}
@@ -3,27 +3,27 @@
 package main
 
 import (
-	"crypto/elliptic"
+	"bufio"
 	"log"
-	"math/big"
 	"os"
 
-	"github.com/emmansun/gmsm/sm2"
+	"github.com/emmansun/sm2elgamal"
 )
 
-// ~1 minute, 65m data
+// ~20 seconds, 15m data
 func main() {
-	size := 1 << 21
-	var bin []byte
-	sm2Curve := sm2.P256()
 	log.Println("start...")
-	for i := 1; i < size; i++ {
-		x, y := sm2Curve.ScalarBaseMult(big.NewInt(int64(i)).Bytes())
-		bin = append(bin, elliptic.MarshalCompressed(sm2Curve, x, y)[:7]...)
-	}
-	err := os.WriteFile("sm2_lookup_table.bin", bin, 0644)
+	f, err := os.Create("sm2_lookup_table.bin")
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err = sm2elgamal.WriteLookupTable(w); err != nil {
+		log.Fatal(err)
+	}
+	if err = w.Flush(); err != nil {
+		log.Fatal(err)
+	}
 	log.Println("end.")
 }
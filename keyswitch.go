@@ -0,0 +1,107 @@
+package sm2elgamal
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"io"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+// maxCiphertextDERLen is the maximum length of a ciphertext in a DER stream.
+const maxCiphertextDERLen = 1024
+
+// KeySwitch re-encrypts c of the old key to newPub without decrypting it,
+// that is (r'G, c2 - d*c1 + r'P_new) with a new random r'. No discrete
+// logarithm is searched, so it is fast and works for any plaintext. The
+// plaintext type and the bound of c are kept.
+func KeySwitch(random io.Reader, oldPriv *sm2.PrivateKey, newPub *ecdsa.PublicKey, c *Ciphertext) (*Ciphertext, error) {
+	return KeySwitchDH(random, newPrivateKey(oldPriv), newPub, c)
+}
+
+// KeySwitchDH is like [KeySwitch] with the old key as a DH key.
+func KeySwitchDH(random io.Reader, oldKey DHKey, newPub *ecdsa.PublicKey, c *Ciphertext) (*Ciphertext, error) {
+	x, y, err := messagePoint(oldKey, c)
+	if err != nil {
+		return nil, err
+	}
+	if newPub == nil || newPub.Curve == nil || !sameCurve(c.curve, newPub.Curve) {
+		return nil, ErrCurveMismatch
+	}
+	r, err := randFieldElement(newPub.Curve, random)
+	if err != nil {
+		return nil, err
+	}
+	x1, y1 := newPub.Curve.ScalarBaseMult(r.Bytes())
+	x2, y2 := newPub.Curve.ScalarMult(newPub.X, newPub.Y, r.Bytes())
+	x2, y2 = newPub.Curve.Add(x2, y2, x, y)
+	ret := newCiphertext(newPub.Curve, x1, y1, x2, y2)
+	ret.scheme, ret.ptype, ret.key = SchemeStandard, c.ptype, PublicKeyFingerprint(newPub)
+	ret.bound = c.bound
+	return ret, nil
+}
+
+// KeySwitchStream reads the DER encoded ciphertexts concatenated in r,
+// switches them from the old key to newPub with [KeySwitchDH], and writes
+// them to w in the same order. It returns the number of ciphertexts written.
+func KeySwitchStream(random io.Reader, oldKey DHKey, newPub *ecdsa.PublicKey, r io.Reader, w io.Writer) (int, error) {
+	br := bufio.NewReader(r)
+	n := 0
+	for {
+		der, err := readDER(br)
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		c, err := Unmarshal(der)
+		if err != nil {
+			return n, err
+		}
+		if c, err = KeySwitchDH(random, oldKey, newPub, c); err != nil {
+			return n, err
+		}
+		if der, err = Marshal(c); err != nil {
+			return n, err
+		}
+		if _, err = w.Write(der); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// readDER reads the next DER element from r, it returns io.EOF if there is
+// none, and ErrInvalidCiphertext if its length is invalid.
+func readDER(r *bufio.Reader) ([]byte, error) {
+	der := make([]byte, 2)
+	if _, err := io.ReadFull(r, der); err != nil {
+		return nil, err
+	}
+	length := int(der[1])
+	if length&0x80 != 0 {
+		lenLen := length & 0x7f
+		if lenLen == 0 || lenLen > 2 {
+			return nil, ErrInvalidCiphertext
+		}
+		lenBytes := make([]byte, lenLen)
+		if _, err := io.ReadFull(r, lenBytes); err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		der = append(der, lenBytes...)
+		length = 0
+		for _, b := range lenBytes {
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxCiphertextDERLen {
+		return nil, ErrInvalidCiphertext
+	}
+	header := len(der)
+	der = append(der, make([]byte, length)...)
+	if _, err := io.ReadFull(r, der[header:]); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return der, nil
+}
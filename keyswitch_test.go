@@ -0,0 +1,86 @@
+package sm2elgamal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func TestKeySwitch(t *testing.T) {
+	oldKey, _ := sm2.GenerateKey(rand.Reader)
+	newKey, _ := sm2.GenerateKey(rand.Reader)
+	c, _ := EncryptInt32(rand.Reader, &oldKey.PublicKey, -123456)
+	c, _ = WithBound(c, big.NewInt(-200000), big.NewInt(0))
+	c2, err := KeySwitch(rand.Reader, oldKey, &newKey.PublicKey, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(c2.C1Bytes(), c.C1Bytes()) {
+		t.Fatal("c1 should be refreshed")
+	}
+	if _, _, ok := c2.Bound(); !ok || c2.PlaintextType() != PlaintextInt32 {
+		t.Fatal("the plaintext type and the bound should be kept")
+	}
+	if v, err := DecryptInt32(newKey, c2); err != nil || v != -123456 {
+		t.Fatalf("expected -123456, got %v, %v", v, err)
+	}
+	if _, err = DecryptInt32(oldKey, c2); err != ErrKeyMismatch {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+	if _, err = KeySwitch(rand.Reader, newKey, &oldKey.PublicKey, c); err != ErrKeyMismatch {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+	if _, err = KeySwitch(rand.Reader, oldKey, nil, c); err != ErrCurveMismatch {
+		t.Fatalf("expected ErrCurveMismatch, got %v", err)
+	}
+}
+
+func TestKeySwitchStream(t *testing.T) {
+	oldKey, _ := sm2.GenerateKey(rand.Reader)
+	newKey, _ := sm2.GenerateKey(rand.Reader)
+	values := []uint32{0, 1, 100, 0xffffffff}
+	var in bytes.Buffer
+	for _, m := range values {
+		c, _ := EncryptUint32(rand.Reader, &oldKey.PublicKey, m)
+		der, _ := Marshal(c)
+		in.Write(der)
+	}
+	data := in.Bytes()
+	var out bytes.Buffer
+	n, err := KeySwitchStream(rand.Reader, NewPrivateKey(oldKey), &newKey.PublicKey, bytes.NewReader(data), &out)
+	if err != nil || n != len(values) {
+		t.Fatalf("expected %d ciphertexts, got %d, %v", len(values), n, err)
+	}
+	r := bufio.NewReader(&out)
+	for _, m := range values {
+		der, err := readDER(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := Unmarshal(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, err := DecryptUint32(newKey, c); err != nil || v != m {
+			t.Fatalf("expected %v, got %v, %v", m, v, err)
+		}
+	}
+
+	n, err = KeySwitchStream(rand.Reader, NewPrivateKey(oldKey), &newKey.PublicKey, bytes.NewReader(data[:len(data)-5]), io.Discard)
+	if err != io.ErrUnexpectedEOF || n != len(values)-1 {
+		t.Fatalf("expected %d ciphertexts and io.ErrUnexpectedEOF, got %d, %v", len(values)-1, n, err)
+	}
+}
+
+func TestReadDERInvalidLength(t *testing.T) {
+	for _, der := range [][]byte{{0x30, 0x80}, {0x30, 0x83, 1, 0, 0}, {0x30, 0x82, 0xff, 0xff}} {
+		if _, err := readDER(bufio.NewReader(bytes.NewReader(der))); err != ErrInvalidCiphertext {
+			t.Fatalf("%x: expected ErrInvalidCiphertext, got %v", der, err)
+		}
+	}
+}
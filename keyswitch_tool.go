@@ -0,0 +1,79 @@
+//go:build ignore
+
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/emmansun/gmsm/sm2"
+	"github.com/emmansun/sm2elgamal"
+)
+
+// go run keyswitch_tool.go -old <private key hex> -new <public key hex> -in old.der -out new.der
+func main() {
+	oldKey := flag.String("old", "", "the old private key in hex")
+	newKey := flag.String("new", "", "the new public key in hex, uncompressed")
+	in := flag.String("in", "", "the file of concatenated DER ciphertexts")
+	out := flag.String("out", "", "the output file, it is only created if all the ciphertexts are switched")
+	flag.Parse()
+
+	b, err := hex.DecodeString(*oldKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	priv, err := sm2.NewPrivateKey(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if b, err = hex.DecodeString(*newKey); err != nil {
+		log.Fatal(err)
+	}
+	pub, err := sm2.NewPublicKey(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	n, err := keySwitchFile(priv, pub, *in, *out)
+	if err != nil {
+		log.Fatalf("after %d ciphertexts: %v", n, err)
+	}
+	log.Printf("%d ciphertexts switched.", n)
+}
+
+// keySwitchFile writes the switched ciphertexts to a temporary file, which
+// is renamed to out on success and removed otherwise, so that out is never
+// left truncated.
+func keySwitchFile(priv *sm2.PrivateKey, pub *ecdsa.PublicKey, in, out string) (n int, err error) {
+	src, err := os.Open(in)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(out), filepath.Base(out)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	w := bufio.NewWriter(tmp)
+	if n, err = sm2elgamal.KeySwitchStream(rand.Reader, sm2elgamal.NewPrivateKey(priv), pub, src, w); err != nil {
+		return n, err
+	}
+	if err = w.Flush(); err != nil {
+		return n, err
+	}
+	if err = tmp.Close(); err != nil {
+		return n, err
+	}
+	return n, os.Rename(tmp.Name(), out)
+}
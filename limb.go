@@ -0,0 +1,142 @@
+package sm2elgamal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// ErrLimbMismatch is returned when limb ciphertexts of different limb width or count are used together.
+var ErrLimbMismatch = errors.New("limb layout mismatch")
+
+// LimbCiphertext is the encryption of a big integer v split into limbs of
+// Bits bits, v = limb[0] + limb[1]*2^Bits + limb[2]*2^(2*Bits) + ...
+//
+// Each limb is encrypted separately and carries its public bound, see
+// [WithBound], so the homomorphic operations work limb by limb without
+// carries, and each limb stays small enough to be decrypted fast. The
+// carries are resolved when the limbs are recombined in decryption.
+type LimbCiphertext struct {
+	bits  uint
+	limbs []*Ciphertext // the least significant limb first
+}
+
+// EncryptLimbs encrypts the non-negative v in n limbs of bits bits with the
+// public key of any scheme, bits must be in [1, 32] and v less than
+// 2^(bits*n), otherwise it returns ErrOutOfRange.
+func EncryptLimbs(random io.Reader, pub PublicKey, v *big.Int, bits uint, n int) (*LimbCiphertext, error) {
+	if bits < 1 || bits > 32 || n < 1 || v.Sign() < 0 || uint(v.BitLen()) > bits*uint(n) {
+		return nil, ErrOutOfRange
+	}
+	max := new(big.Int).Lsh(big.NewInt(1), bits)
+	max.Sub(max, big.NewInt(1))
+	ret := &LimbCiphertext{bits: bits, limbs: make([]*Ciphertext, n)}
+	rest := new(big.Int).Set(v)
+	for i := range ret.limbs {
+		limb := new(big.Int).And(rest, max)
+		rest.Rsh(rest, bits)
+		c, err := EncryptBounded(random, pub, limb, new(big.Int), max)
+		if err != nil {
+			return nil, err
+		}
+		ret.limbs[i] = c
+	}
+	return ret, nil
+}
+
+// Bits returns the limb width.
+func (c *LimbCiphertext) Bits() uint {
+	return c.bits
+}
+
+// Limbs returns the limb ciphertexts, the least significant limb first.
+func (c *LimbCiphertext) Limbs() []*Ciphertext {
+	return append([]*Ciphertext(nil), c.limbs...)
+}
+
+// limbWise applies op to the limbs of c and other.
+func (c *LimbCiphertext) limbWise(other *LimbCiphertext, op func(a, b *Ciphertext) (*Ciphertext, error)) (*LimbCiphertext, error) {
+	if c == nil || other == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	if c.bits != other.bits || len(c.limbs) != len(other.limbs) {
+		return nil, ErrLimbMismatch
+	}
+	ret := &LimbCiphertext{bits: c.bits, limbs: make([]*Ciphertext, len(c.limbs))}
+	for i := range c.limbs {
+		limb, err := op(c.limbs[i], other.limbs[i])
+		if err != nil {
+			return nil, err
+		}
+		ret.limbs[i] = limb
+	}
+	return ret, nil
+}
+
+// Add returns c + other.
+func (c *LimbCiphertext) Add(other *LimbCiphertext) (*LimbCiphertext, error) {
+	return c.limbWise(other, Add)
+}
+
+// Sub returns c - other, the limbs can become negative, the result is
+// negative if other is greater than c.
+func (c *LimbCiphertext) Sub(other *LimbCiphertext) (*LimbCiphertext, error) {
+	return c.limbWise(other, Sub)
+}
+
+// ScalarMult returns k * c.
+func (c *LimbCiphertext) ScalarMult(k *big.Int) (*LimbCiphertext, error) {
+	return c.limbWise(c, func(a, _ *Ciphertext) (*Ciphertext, error) {
+		return ScalarMult(a, k)
+	})
+}
+
+// SumLimbs returns cumulative sum value.
+func SumLimbs(values ...*LimbCiphertext) (*LimbCiphertext, error) {
+	if len(values) == 0 {
+		return nil, ErrNoCiphertext
+	}
+	limbs := make([][]*Ciphertext, len(values[0].limbs))
+	for _, v := range values {
+		if v == nil {
+			return nil, ErrInvalidCiphertext
+		}
+		if v.bits != values[0].bits || len(v.limbs) != len(limbs) {
+			return nil, ErrLimbMismatch
+		}
+		for i, limb := range v.limbs {
+			limbs[i] = append(limbs[i], limb)
+		}
+	}
+	ret := &LimbCiphertext{bits: values[0].bits, limbs: make([]*Ciphertext, len(limbs))}
+	for i := range limbs {
+		limb, err := Sum(limbs[i]...)
+		if err != nil {
+			return nil, err
+		}
+		ret.limbs[i] = limb
+	}
+	return ret, nil
+}
+
+// Decrypt decrypts each limb in its bound and recombines them with carries.
+func (c *LimbCiphertext) Decrypt(priv DHKey) (*big.Int, error) {
+	if c == nil || len(c.limbs) == 0 {
+		return nil, ErrInvalidCiphertext
+	}
+	ret := new(big.Int)
+	for i := len(c.limbs) - 1; i >= 0; i-- {
+		limb := c.limbs[i]
+		x, y, err := messagePoint(priv, limb)
+		if err != nil {
+			return nil, err
+		}
+		m, err := searchInterval(context.Background(), priv.GetCurve(), x, y, limb.bound.intersect(searchableRange))
+		if err != nil {
+			return nil, err
+		}
+		ret.Lsh(ret, c.bits).Add(ret, m)
+	}
+	return ret, nil
+}
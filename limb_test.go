@@ -0,0 +1,84 @@
+package sm2elgamal
+
+import (
+	"crypto/rand"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestLimbCiphertext(t *testing.T) {
+	sk := NewTwistedSecretKey(priv)
+	pub := sk.Public()
+	values := []*big.Int{
+		new(big.Int).SetUint64(math.MaxUint64),
+		new(big.Int).SetUint64(math.MaxUint64 - 12345),
+		big.NewInt(1),
+		new(big.Int).SetUint64(0xffff0000ffff),
+	}
+	limbs := make([]*LimbCiphertext, len(values))
+	expected := new(big.Int)
+	for i, v := range values {
+		c, err := EncryptLimbs(rand.Reader, pub, v, 16, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		limbs[i] = c
+		expected.Add(expected, v)
+	}
+	sum, err := SumLimbs(limbs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum, err = sum.Add(limbs[0]); err != nil {
+		t.Fatal(err)
+	}
+	expected.Add(expected, values[0])
+	if sum, err = sum.ScalarMult(big.NewInt(3)); err != nil {
+		t.Fatal(err)
+	}
+	expected.Mul(expected, big.NewInt(3))
+	v, err := sum.Decrypt(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Cmp(expected) != 0 {
+		t.Fatalf("expected %v, got %v", expected, v)
+	}
+
+	diff, err := limbs[2].Sub(limbs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err = diff.Decrypt(sk); err != nil {
+		t.Fatal(err)
+	}
+	if expected.Sub(values[2], values[0]); v.Cmp(expected) != 0 {
+		t.Fatalf("expected %v, got %v", expected, v)
+	}
+}
+
+func TestLimbCiphertextErrors(t *testing.T) {
+	pub := NewTwistedSecretKey(priv).Public()
+	if _, err := EncryptLimbs(rand.Reader, pub, big.NewInt(1<<16), 16, 1); err != ErrOutOfRange {
+		t.Fatalf("expected ErrOutOfRange, got %v", err)
+	}
+	if _, err := EncryptLimbs(rand.Reader, pub, big.NewInt(-1), 16, 1); err != ErrOutOfRange {
+		t.Fatalf("expected ErrOutOfRange, got %v", err)
+	}
+	a, _ := EncryptLimbs(rand.Reader, pub, big.NewInt(1), 16, 2)
+	b, _ := EncryptLimbs(rand.Reader, pub, big.NewInt(1), 8, 2)
+	c, _ := EncryptLimbs(rand.Reader, pub, big.NewInt(1), 16, 3)
+	if _, err := a.Add(b); err != ErrLimbMismatch {
+		t.Fatalf("expected ErrLimbMismatch, got %v", err)
+	}
+	if _, err := SumLimbs(a, c); err != ErrLimbMismatch {
+		t.Fatalf("expected ErrLimbMismatch, got %v", err)
+	}
+	if _, err := SumLimbs(); err != ErrNoCiphertext {
+		t.Fatalf("expected ErrNoCiphertext, got %v", err)
+	}
+	if len(a.Limbs()) != 2 || a.Bits() != 16 {
+		t.Fatal("unexpected layout")
+	}
+}
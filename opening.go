@@ -0,0 +1,65 @@
+package sm2elgamal
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// ErrInvalidNonce is returned when the supplied randomness is not in [1, N).
+var ErrInvalidNonce = errors.New("nonce is out of range")
+
+// Opening is the plaintext and the randomness of a ciphertext, with them
+// anyone can check what the ciphertext encrypts, without the private key.
+type Opening struct {
+	M *big.Int // plaintext
+	R *big.Int // randomness in [1, N)
+}
+
+func checkNonce(curve elliptic.Curve, r *big.Int) error {
+	if r == nil || r.Sign() <= 0 || r.Cmp(curve.Params().N) >= 0 {
+		return ErrInvalidNonce
+	}
+	return nil
+}
+
+// sameCiphertext reports whether a and b have the same points.
+func sameCiphertext(a, b *Ciphertext) bool {
+	return a != nil && b != nil && bytes.Equal(a.c1, b.c1) && bytes.Equal(a.c2, b.c2)
+}
+
+// EncryptWithNonce encrypts m with the publickey and the caller supplied
+// randomness r, m is reduced modulo N so it can be negative. r must be in
+// [1, N), kept secret and never reused, otherwise the plaintexts are exposed.
+func EncryptWithNonce(pub *ecdsa.PublicKey, m, r *big.Int) (*Ciphertext, error) {
+	if err := checkNonce(pub.Curve, r); err != nil {
+		return nil, err
+	}
+	return encrypt(pub, new(big.Int).Mod(m, pub.Curve.Params().N), r), nil
+}
+
+// EncryptReturningOpening encrypts m with the publickey, and returns the
+// opening of the ciphertext too.
+func EncryptReturningOpening(random io.Reader, pub *ecdsa.PublicKey, m *big.Int) (*Ciphertext, *Opening, error) {
+	r, err := randFieldElement(pub.Curve, random)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := EncryptWithNonce(pub, m, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, &Opening{M: new(big.Int).Set(m), R: r}, nil
+}
+
+// VerifyOpening reports whether c is the encryption of o.M with randomness o.R.
+func VerifyOpening(pub *ecdsa.PublicKey, c *Ciphertext, o *Opening) bool {
+	if o == nil || o.M == nil {
+		return false
+	}
+	expected, err := EncryptWithNonce(pub, o.M, o.R)
+	return err == nil && sameCiphertext(c, expected)
+}
@@ -0,0 +1,63 @@
+package sm2elgamal
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func TestEncryptReturningOpening(t *testing.T) {
+	std, _ := sm2.GenerateKey(rand.Reader)
+	c, o, err := EncryptReturningOpening(rand.Reader, &std.PublicKey, big.NewInt(-42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := DecryptInt32(std, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != -42 {
+		t.Fatalf("expected %d, got %d", -42, v)
+	}
+	if !VerifyOpening(&std.PublicKey, c, o) {
+		t.Fatal("opening should be valid")
+	}
+	if VerifyOpening(&std.PublicKey, c, &Opening{M: big.NewInt(42), R: o.R}) {
+		t.Fatal("opening should be invalid")
+	}
+	c2, err := EncryptWithNonce(&std.PublicKey, o.M, o.R)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameCiphertext(c, c2) {
+		t.Fatal("not same")
+	}
+	for _, r := range []*big.Int{nil, big.NewInt(0), c.curve.Params().N} {
+		if _, err = EncryptWithNonce(&std.PublicKey, o.M, r); err != ErrInvalidNonce {
+			t.Fatalf("expected ErrInvalidNonce, got %v", err)
+		}
+	}
+}
+
+func TestTwistedEncryptReturningOpening(t *testing.T) {
+	te := FromPrivateKey(priv)
+	c, o, err := te.EncryptReturningOpening(rand.Reader, &priv.PublicKey, big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := priv.DecryptUint32(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 42 {
+		t.Fatalf("expected %d, got %d", 42, v)
+	}
+	if !te.VerifyOpening(&priv.PublicKey, c, o) {
+		t.Fatal("opening should be valid")
+	}
+	if te.VerifyOpening(&priv.PublicKey, c, &Opening{M: o.M, R: new(big.Int).Add(o.R, big.NewInt(1))}) {
+		t.Fatal("opening should be invalid")
+	}
+}
@@ -0,0 +1,308 @@
+package sm2elgamal
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+var (
+	// ErrInvalidCiphertext is returned when a ciphertext is nil or its points are not on the curve.
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+	// ErrCurveMismatch is returned when ciphertexts or keys of different curves are used together.
+	ErrCurveMismatch = errors.New("curve mismatch")
+	// ErrNoCiphertext is returned when there is no ciphertext to sum.
+	ErrNoCiphertext = errors.New("no ciphertext")
+	// ErrZeroDivisor is returned when dividing a ciphertext by a multiple of N.
+	ErrZeroDivisor = errors.New("divisor is zero modulo N")
+)
+
+// marshalPoint converts a point into compressed form, the point at infinity
+// is encoded as a single zero byte.
+func marshalPoint(curve elliptic.Curve, x, y *big.Int) []byte {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return []byte{0}
+	}
+	return elliptic.MarshalCompressed(curve, x, y)
+}
+
+// unmarshalPoint converts a point, serialized by marshalPoint, into an x, y pair.
+func unmarshalPoint(curve elliptic.Curve, data []byte) (x, y *big.Int, err error) {
+	if len(data) == 1 && data[0] == 0 {
+		return new(big.Int), new(big.Int), nil
+	}
+	x, y = elliptic.UnmarshalCompressed(curve, data)
+	if x == nil {
+		return nil, nil, ErrInvalidCiphertext
+	}
+	return x, y, nil
+}
+
+func newCiphertext(curve elliptic.Curve, x1, y1, x2, y2 *big.Int) *Ciphertext {
+	return &Ciphertext{curve: curve, c1: marshalPoint(curve, x1, y1), c2: marshalPoint(curve, x2, y2)}
+}
+
+// points returns the two points of the ciphertext.
+func (c *Ciphertext) points() (x1, y1, x2, y2 *big.Int, err error) {
+	if c == nil || c.curve == nil {
+		return nil, nil, nil, nil, ErrInvalidCiphertext
+	}
+	if x1, y1, err = unmarshalPoint(c.curve, c.c1); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if x2, y2, err = unmarshalPoint(c.curve, c.c2); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return x1, y1, x2, y2, nil
+}
+
+// sameCurve reports whether a and b are the same curve.
+func sameCurve(a, b elliptic.Curve) bool {
+	return a == b || a.Params().Name == b.Params().Name
+}
+
+// apply sets ret to op(values...). The methods of Ciphertext can't return
+// an error, so if the plaintext types or the bounds of the values conflict,
+// op is done again without them, the plaintext type is kept only if it is
+// shared by all the values, and the bound is dropped. It panics if a value
+// is invalid, or the values are of different schemes or keys.
+func (ret *Ciphertext) apply(op func(values []*Ciphertext) (*Ciphertext, error), values ...*Ciphertext) *Ciphertext {
+	c, err := op(values)
+	if err == ErrPlaintextTypeMismatch || err == ErrBoundExceeded {
+		relaxed := make([]*Ciphertext, len(values))
+		for i, v := range values {
+			if v == nil {
+				panic(ErrInvalidCiphertext)
+			}
+			u := *v
+			u.ptype, u.bound = 0, nil
+			relaxed[i] = &u
+		}
+		if c, err = op(relaxed); err == nil {
+			c.ptype = commonTags(values).ptype
+		}
+	}
+	if err != nil {
+		panic(err)
+	}
+	*ret = *c
+	return ret
+}
+
+// Add returns c1 + c2.
+func Add(c1, c2 *Ciphertext) (*Ciphertext, error) {
+	x11, y11, x12, y12, err := c1.points()
+	if err != nil {
+		return nil, err
+	}
+	x21, y21, x22, y22, err := c2.points()
+	if err != nil {
+		return nil, err
+	}
+	if !sameCurve(c1.curve, c2.curve) {
+		return nil, ErrCurveMismatch
+	}
+	t, err := mergeTags(c1.tags, c2.tags)
+	if err != nil {
+		return nil, err
+	}
+	curve := c1.curve
+	x31, y31 := curve.Add(x11, y11, x21, y21)
+	x32, y32 := curve.Add(x12, y12, x22, y22)
+	ret := newCiphertext(curve, x31, y31, x32, y32)
+	ret.tags = t
+	ret.bound = c1.bound.add(c2.bound)
+	if err := ret.checkBound(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Sum returns cumulative sum value.
+func Sum(values ...*Ciphertext) (*Ciphertext, error) {
+	if len(values) == 0 {
+		return nil, ErrNoCiphertext
+	}
+	v0 := values[0]
+	x1, y1, x2, y2, err := v0.points()
+	if err != nil {
+		return nil, err
+	}
+	t, bound := v0.tags, v0.bound
+	for _, vi := range values[1:] {
+		xi1, yi1, xi2, yi2, err := vi.points()
+		if err != nil {
+			return nil, err
+		}
+		if !sameCurve(v0.curve, vi.curve) {
+			return nil, ErrCurveMismatch
+		}
+		if t, err = mergeTags(t, vi.tags); err != nil {
+			return nil, err
+		}
+		bound = bound.add(vi.bound)
+		x1, y1 = v0.curve.Add(x1, y1, xi1, yi1)
+		x2, y2 = v0.curve.Add(x2, y2, xi2, yi2)
+	}
+	ret := newCiphertext(v0.curve, x1, y1, x2, y2)
+	ret.tags, ret.bound = t, bound
+	if err := ret.checkBound(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Sub returns c1 - c2.
+func Sub(c1, c2 *Ciphertext) (*Ciphertext, error) {
+	if _, _, _, _, err := c1.points(); err != nil {
+		return nil, err
+	}
+	if _, _, _, _, err := c2.points(); err != nil {
+		return nil, err
+	}
+	if _, err := mergeTags(c1.tags, c2.tags); err != nil {
+		return nil, err
+	}
+	return Add(c1.signed(), neg(c2))
+}
+
+// Neg returns -c, it only flips the sign of the two points so it is much
+// cheaper than a scalar multiplication.
+func Neg(c *Ciphertext) (*Ciphertext, error) {
+	if _, _, _, _, err := c.points(); err != nil {
+		return nil, err
+	}
+	ret := neg(c)
+	if err := ret.checkBound(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// neg returns -c without checking its bound, c must be valid.
+func neg(c *Ciphertext) *Ciphertext {
+	ret := *c.signed()
+	ret.c1 = negPoint(c.c1)
+	ret.c2 = negPoint(c.c2)
+	ret.bound = c.bound.neg()
+	return &ret
+}
+
+// negPoint negates a point serialized by marshalPoint.
+func negPoint(p []byte) []byte {
+	q := bytes.Clone(p)
+	if len(q) > 1 {
+		// 2 <-> 3, the parity of y
+		q[0] ^= 1
+	}
+	return q
+}
+
+// AddPlain returns c + k, the plaintext k is added to c directly without
+// encryption. k may be negative. It works for both the standard and the
+// Twisted ciphertexts, since kG is added to the second point in both schemes.
+func AddPlain(c *Ciphertext, k *big.Int) (*Ciphertext, error) {
+	x1, y1, x2, y2, err := c.points()
+	if err != nil {
+		return nil, err
+	}
+	kx, ky := c.curve.ScalarBaseMult(new(big.Int).Mod(k, c.curve.Params().N).Bytes())
+	x2, y2 = c.curve.Add(x2, y2, kx, ky)
+	ret := newCiphertext(c.curve, x1, y1, x2, y2)
+	ret.tags = c.tags
+	ret.bound = c.bound.add(&interval{k, k})
+	if err := ret.checkBound(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// SubPlain returns c - k, see [AddPlain].
+func SubPlain(c *Ciphertext, k *big.Int) (*Ciphertext, error) {
+	return AddPlain(c, new(big.Int).Neg(k))
+}
+
+// TrivialEncrypt returns the encryption of k with r = 0, that is (O, kG), it
+// can be decrypted by any SM2 key of both the standard and the Twisted
+// scheme. It is not hiding at all, its use is to be combined with other
+// ciphertexts, or to be re-randomized.
+func TrivialEncrypt(k *big.Int) *Ciphertext {
+	curve := sm2.P256()
+	x, y := curve.ScalarBaseMult(new(big.Int).Mod(k, curve.Params().N).Bytes())
+	ret := newCiphertext(curve, new(big.Int), new(big.Int), x, y)
+	ret.bound = &interval{new(big.Int).Set(k), new(big.Int).Set(k)}
+	return ret
+}
+
+// ScalarMultUint32 scalar mutiples the ciphertext with m,
+// the result is an encryption of zero if m is zero.
+func ScalarMultUint32(c *Ciphertext, m uint32) (*Ciphertext, error) {
+	return ScalarMult(c, new(big.Int).SetUint64(uint64(m)))
+}
+
+// ScalarMultInt32 scalar mutiples the ciphertext with m,
+// the result is an encryption of zero if m is zero.
+func ScalarMultInt32(c *Ciphertext, m int32) (*Ciphertext, error) {
+	return ScalarMult(c, big.NewInt(int64(m)))
+}
+
+// ScalarMult scalar mutiples the ciphertext with k, k is reduced modulo N
+// so it can be negative or larger than N.
+func ScalarMult(c *Ciphertext, k *big.Int) (*Ciphertext, error) {
+	ret, err := scalarMult(c, k)
+	if err != nil {
+		return nil, err
+	}
+	ret.bound = c.bound.mul(k)
+	if err := ret.checkBound(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// scalarMult is ScalarMult without the bound.
+func scalarMult(c *Ciphertext, k *big.Int) (*Ciphertext, error) {
+	x1, y1, x2, y2, err := c.points()
+	if err != nil {
+		return nil, err
+	}
+	scalar := new(big.Int).Mod(k, c.curve.Params().N).Bytes()
+	x1, y1 = c.curve.ScalarMult(x1, y1, scalar)
+	x2, y2 = c.curve.ScalarMult(x2, y2, scalar)
+	ret := newCiphertext(c.curve, x1, y1, x2, y2)
+	ret.tags = c.tags
+	return ret, nil
+}
+
+// DivExact returns c / k, that is c multiplied by the inverse of k modulo N.
+// If the plaintext is a multiple of k, the result decrypts to the exact
+// quotient, otherwise it decrypts to a huge value which overflows.
+// It returns ErrZeroDivisor if k is zero modulo N.
+func DivExact(c *Ciphertext, k *big.Int) (*Ciphertext, error) {
+	if c == nil || c.curve == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	N := c.curve.Params().N
+	kMod := new(big.Int).Mod(k, N)
+	if kMod.Sign() == 0 {
+		return nil, ErrZeroDivisor
+	}
+	var kInv *big.Int
+	if in, ok := c.curve.(invertible); ok {
+		kInv = in.Inverse(kMod)
+	} else {
+		kInv = fermatInverse(kMod, N)
+	}
+	ret, err := scalarMult(c, kInv)
+	if err != nil {
+		return nil, err
+	}
+	ret.bound = c.bound.div(k)
+	if err := ret.checkBound(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
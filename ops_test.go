@@ -0,0 +1,198 @@
+package sm2elgamal
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func TestOpsInvalidCiphertext(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	c, err := EncryptUint32(rand.Reader, &priv.PublicKey, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	malformed := &Ciphertext{curve: c.curve, c1: c.c1, c2: []byte{2, 1, 2, 3}}
+	g := elliptic.MarshalCompressed(elliptic.P256(), elliptic.P256().Params().Gx, elliptic.P256().Params().Gy)
+	p256 := &Ciphertext{curve: elliptic.P256(), c1: g, c2: g}
+
+	if _, err = Add(c, malformed); err != ErrInvalidCiphertext {
+		t.Errorf("Add: expected ErrInvalidCiphertext, got %v", err)
+	}
+	if _, err = Add(c, nil); err != ErrInvalidCiphertext {
+		t.Errorf("Add: expected ErrInvalidCiphertext, got %v", err)
+	}
+	if _, err = Sub(c, p256); err != ErrCurveMismatch {
+		t.Errorf("Sub: expected ErrCurveMismatch, got %v", err)
+	}
+	if _, err = Sum(); err != ErrNoCiphertext {
+		t.Errorf("Sum: expected ErrNoCiphertext, got %v", err)
+	}
+	if _, err = Sum(c, c, &Ciphertext{}); err != ErrInvalidCiphertext {
+		t.Errorf("Sum: expected ErrInvalidCiphertext, got %v", err)
+	}
+	if _, err = ScalarMultInt32(malformed, 2); err != ErrInvalidCiphertext {
+		t.Errorf("ScalarMultInt32: expected ErrInvalidCiphertext, got %v", err)
+	}
+	if _, err = DecryptUint32(priv, malformed); err != ErrInvalidCiphertext {
+		t.Errorf("DecryptUint32: expected ErrInvalidCiphertext, got %v", err)
+	}
+	if _, err = DecryptInt32(priv, p256); err != ErrCurveMismatch {
+		t.Errorf("DecryptInt32: expected ErrCurveMismatch, got %v", err)
+	}
+	der, err := Marshal(malformed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Unmarshal(der); err != ErrInvalidCiphertext {
+		t.Errorf("Unmarshal: expected ErrInvalidCiphertext, got %v", err)
+	}
+}
+
+func TestOpsZero(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	c, err := EncryptInt32(rand.Reader, &priv.PublicKey, -5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero1, err := ScalarMultInt32(c, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero2, err := Sub(c, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, zero := range []*Ciphertext{zero1, zero2} {
+		der, err := Marshal(zero)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zero, err = Unmarshal(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum, err := Add(zero, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := DecryptInt32(priv, sum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != -5 {
+			t.Fatalf("expected %d, got %d", -5, v)
+		}
+		v, err = DecryptInt32(priv, zero)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != 0 {
+			t.Fatalf("expected 0, got %d", v)
+		}
+	}
+}
+
+func TestNegAndPlain(t *testing.T) {
+	std, _ := sm2.GenerateKey(rand.Reader)
+	encrypt := map[string]func(m int32) (*Ciphertext, error){
+		"standard": func(m int32) (*Ciphertext, error) { return EncryptInt32(rand.Reader, &std.PublicKey, m) },
+		"twisted":  func(m int32) (*Ciphertext, error) { return priv.EncryptInt32(rand.Reader, m) },
+	}
+	decrypt := map[string]func(c *Ciphertext) (int32, error){
+		"standard": func(c *Ciphertext) (int32, error) { return DecryptInt32(std, c) },
+		"twisted":  priv.DecryptInt32,
+	}
+	for name, enc := range encrypt {
+		dec := decrypt[name]
+		check := func(c *Ciphertext, err error, expected int32) {
+			t.Helper()
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := dec(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != expected {
+				t.Fatalf("%s: expected %d, got %d", name, expected, v)
+			}
+		}
+		c, err := enc(7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		neg, err := Neg(c)
+		check(neg, err, -7)
+		c2, err := AddPlain(c, big.NewInt(10))
+		check(c2, err, 17)
+		c2, err = SubPlain(c, big.NewInt(10))
+		check(c2, err, -3)
+		c2, err = Add(c, TrivialEncrypt(big.NewInt(-8)))
+		check(c2, err, -1)
+		c2, err = Sub(c, c)
+		check(c2, err, 0)
+		check(TrivialEncrypt(big.NewInt(1000)), nil, 1000)
+	}
+}
+
+func TestScalarMultAndDivExact(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	c, err := EncryptInt32(rand.Reader, &priv.PublicKey, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	N := c.curve.Params().N
+	cases := []struct {
+		k        *big.Int
+		expected int32
+	}{
+		{big.NewInt(-7), -42},
+		{new(big.Int).Add(N, big.NewInt(3)), 18},
+		{big.NewInt(0), 0},
+	}
+	for _, tc := range cases {
+		ret, err := ScalarMult(c, tc.k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := DecryptInt32(priv, ret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != tc.expected {
+			t.Fatalf("expected %d, got %d", tc.expected, v)
+		}
+	}
+
+	shares := make([]*Ciphertext, 5)
+	for i := range shares {
+		shares[i], err = EncryptInt32(rand.Reader, &priv.PublicKey, -300)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	sum, err := Sum(shares...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []int64{5, -3} {
+		ret, err := DivExact(sum, big.NewInt(k))
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := DecryptInt32(priv, ret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(v) != -1500/k {
+			t.Fatalf("expected %d, got %d", -1500/k, v)
+		}
+	}
+	if _, err = DivExact(sum, N); err != ErrZeroDivisor {
+		t.Fatalf("expected ErrZeroDivisor, got %v", err)
+	}
+}
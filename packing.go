@@ -0,0 +1,233 @@
+package sm2elgamal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"slices"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+var (
+	// ErrSlotOverflow is returned when a packed slot exceeds its width, or
+	// more packed ciphertexts are added than the headroom allows.
+	ErrSlotOverflow = errors.New("packed slot overflow")
+	// ErrPackingMismatch is returned when packed ciphertexts of different packings are used together.
+	ErrPackingMismatch = errors.New("packing mismatch")
+	// ErrInvalidPacking is returned when the slot widths or the number of additions are invalid.
+	ErrInvalidPacking = errors.New("invalid packing")
+)
+
+// maxPackedBits is the total width of the slots, so that the packed
+// plaintexts are decrypted as uint32.
+const maxPackedBits = 32
+
+// Packing packs several small non-negative values into one uint32 plaintext.
+//
+// Each slot has its value width plus the same headroom bits above it, so
+// that the sum of up to 2^headroom packed plaintexts never carries from a
+// slot into the next one. A slot whose sum is wider than its value width
+// is reported as overflowed when unpacked.
+type Packing struct {
+	widths   []uint
+	headroom uint
+}
+
+// NewPacking creates a packing of the value widths, the first slot is the
+// least significant one, with the headroom of the given number of additions.
+// The total width of the slots, including the headroom, must not exceed 32 bits.
+func NewPacking(widths []uint, additions int) (*Packing, error) {
+	if len(widths) == 0 || additions < 0 {
+		return nil, ErrInvalidPacking
+	}
+	p := &Packing{widths: slices.Clone(widths), headroom: uint(bits.Len(uint(additions)))}
+	total := uint(0)
+	for _, w := range widths {
+		if w == 0 {
+			return nil, ErrInvalidPacking
+		}
+		total += w + p.headroom
+	}
+	if total > maxPackedBits {
+		return nil, fmt.Errorf("%w: %d bits of slots exceed %d bits", ErrInvalidPacking, total, maxPackedBits)
+	}
+	return p, nil
+}
+
+// Slots returns the number of slots.
+func (p *Packing) Slots() int {
+	return len(p.widths)
+}
+
+// maxTerms returns how many packed plaintexts can be summed.
+func (p *Packing) maxTerms() int {
+	return 1 << p.headroom
+}
+
+func (p *Packing) equal(q *Packing) bool {
+	return p == q || p.headroom == q.headroom && slices.Equal(p.widths, q.widths)
+}
+
+// Pack packs the values, it returns ErrOutOfRange if the number of values
+// is not the number of slots or a value is wider than its slot.
+func (p *Packing) Pack(values []uint32) (uint32, error) {
+	if len(values) != len(p.widths) {
+		return 0, ErrOutOfRange
+	}
+	var m uint32
+	shift := uint(0)
+	for i, v := range values {
+		if uint(bits.Len32(v)) > p.widths[i] {
+			return 0, ErrOutOfRange
+		}
+		m |= v << shift
+		shift += p.widths[i] + p.headroom
+	}
+	return m, nil
+}
+
+// Unpack unpacks the slots of m, the slots are returned even if some of
+// them overflowed, in which case the error wraps ErrSlotOverflow.
+func (p *Packing) Unpack(m uint32) ([]uint32, error) {
+	values := make([]uint32, len(p.widths))
+	var overflowed []int
+	shift := uint(0)
+	for i, w := range p.widths {
+		slot := uint64(m) >> shift & (1<<(w+p.headroom) - 1)
+		values[i] = uint32(slot)
+		if slot >= 1<<w {
+			overflowed = append(overflowed, i)
+		}
+		shift += w + p.headroom
+	}
+	if overflowed != nil {
+		return values, fmt.Errorf("%w: slots %v", ErrSlotOverflow, overflowed)
+	}
+	return values, nil
+}
+
+// PackedCiphertext is the encryption of packed values, it keeps the number
+// of packed plaintexts summed in it, which is public.
+type PackedCiphertext struct {
+	packing *Packing
+	c       *Ciphertext
+	terms   int
+}
+
+// Encrypt packs the values and encrypts them with the public key of any scheme.
+func (p *Packing) Encrypt(random io.Reader, pub PublicKey, values []uint32) (*PackedCiphertext, error) {
+	m, err := p.Pack(values)
+	if err != nil {
+		return nil, err
+	}
+	c, err := pub.EncryptUint32(random, m)
+	if err != nil {
+		return nil, err
+	}
+	return &PackedCiphertext{packing: p, c: c, terms: 1}, nil
+}
+
+// NewPackedCiphertext rebuilds the packed ciphertext from its underlying
+// ciphertext, its packing and the number of packed plaintexts summed in it,
+// for example after they are stored separately. It returns ErrSlotOverflow
+// if terms exceeds the headroom of the packing.
+func NewPackedCiphertext(p *Packing, c *Ciphertext, terms int) (*PackedCiphertext, error) {
+	if p == nil || terms < 1 {
+		return nil, ErrInvalidPacking
+	}
+	if _, _, _, _, err := c.points(); err != nil {
+		return nil, err
+	}
+	if terms > p.maxTerms() {
+		return nil, ErrSlotOverflow
+	}
+	return &PackedCiphertext{packing: p, c: c, terms: terms}, nil
+}
+
+// Ciphertext returns the underlying ciphertext.
+func (c *PackedCiphertext) Ciphertext() *Ciphertext {
+	return c.c
+}
+
+// Terms returns the number of packed plaintexts summed in the ciphertext.
+func (c *PackedCiphertext) Terms() int {
+	return c.terms
+}
+
+// Add returns c + other slot by slot, it returns ErrSlotOverflow if the sum
+// would exceed the headroom of the packing.
+func (c *PackedCiphertext) Add(other *PackedCiphertext) (*PackedCiphertext, error) {
+	if c == nil || other == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	if !c.packing.equal(other.packing) {
+		return nil, ErrPackingMismatch
+	}
+	if c.terms+other.terms > c.packing.maxTerms() {
+		return nil, ErrSlotOverflow
+	}
+	sum, err := Add(c.c, other.c)
+	if err != nil {
+		return nil, err
+	}
+	return &PackedCiphertext{packing: c.packing, c: sum, terms: c.terms + other.terms}, nil
+}
+
+// Decrypt decrypts and unpacks the ciphertext, see [Packing.Unpack].
+func (c *PackedCiphertext) Decrypt(priv DHKey) ([]uint32, error) {
+	if c == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	m, err := decryptUint32(priv, c.c)
+	if err != nil {
+		return nil, err
+	}
+	return c.packing.Unpack(m)
+}
+
+// MarshalPacked converts the packed ciphertext to ASN.1 DER form, the packing
+// itself is not included, it is part of the configuration.
+//
+//	PackedCiphertext ::= SEQUENCE {
+//	  ciphertext  Ciphertext, -- see [Marshal]
+//	  terms       INTEGER }
+func MarshalPacked(c *PackedCiphertext) ([]byte, error) {
+	if c == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	der, err := Marshal(c.c)
+	if err != nil {
+		return nil, err
+	}
+	var b cryptobyte.Builder
+	b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddBytes(der)
+		b.AddASN1Int64(int64(c.terms))
+	})
+	return b.Bytes()
+}
+
+// UnmarshalPacked parses the packed ciphertext of the packing p in ASN.1 DER
+// form, see [NewPackedCiphertext].
+func UnmarshalPacked(p *Packing, der []byte) (*PackedCiphertext, error) {
+	var (
+		inner, ciphertext cryptobyte.String
+		terms             int
+	)
+	input := cryptobyte.String(der)
+	if !input.ReadASN1(&inner, asn1.SEQUENCE) ||
+		!input.Empty() ||
+		!inner.ReadASN1Element(&ciphertext, asn1.SEQUENCE) ||
+		!inner.ReadASN1Integer(&terms) ||
+		!inner.Empty() {
+		return nil, errors.New("invalid asn1 format packed ciphertext")
+	}
+	c, err := Unmarshal(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return NewPackedCiphertext(p, c, terms)
+}
@@ -0,0 +1,100 @@
+package sm2elgamal
+
+import (
+	"crypto/rand"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestPacking(t *testing.T) {
+	// 3 slots of 8 bits with 2 bits of headroom
+	p, err := NewPacking([]uint{8, 8, 8}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewPacking([]uint{8, 8, 8}, 4); !errors.Is(err, ErrInvalidPacking) {
+		t.Fatalf("expected ErrInvalidPacking, got %v", err)
+	}
+	if p.Slots() != 3 {
+		t.Fatalf("expected 3 slots, got %d", p.Slots())
+	}
+	if _, err = p.Pack([]uint32{256, 0, 0}); err != ErrOutOfRange {
+		t.Fatalf("expected ErrOutOfRange, got %v", err)
+	}
+	if _, err = p.Pack([]uint32{1, 2}); err != ErrOutOfRange {
+		t.Fatalf("expected ErrOutOfRange, got %v", err)
+	}
+
+	sk := NewTwistedSecretKey(priv)
+	values := [][]uint32{{255, 0, 7}, {1, 100, 8}, {0, 50, 9}, {0, 1, 10}}
+	var sum *PackedCiphertext
+	for _, v := range values {
+		c, err := p.Encrypt(rand.Reader, sk.Public(), v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum == nil {
+			sum = c
+		} else if sum, err = sum.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := sum.Decrypt(sk)
+	if !errors.Is(err, ErrSlotOverflow) {
+		t.Fatalf("expected ErrSlotOverflow, got %v", err)
+	}
+	if !slices.Equal(got, []uint32{256, 151, 34}) {
+		t.Fatalf("unexpected slots %v", got)
+	}
+	if _, err = sum.Add(sum); err != ErrSlotOverflow {
+		t.Fatalf("expected ErrSlotOverflow, got %v", err)
+	}
+
+	c, _ := p.Encrypt(rand.Reader, sk.Public(), []uint32{1, 2, 3})
+	if got, err = c.Decrypt(sk); err != nil || !slices.Equal(got, []uint32{1, 2, 3}) {
+		t.Fatalf("unexpected slots %v, %v", got, err)
+	}
+	q, _ := NewPacking([]uint{8, 8, 8}, 1)
+	d, _ := q.Encrypt(rand.Reader, sk.Public(), []uint32{1, 2, 3})
+	if _, err = c.Add(d); err != ErrPackingMismatch {
+		t.Fatalf("expected ErrPackingMismatch, got %v", err)
+	}
+}
+
+func TestPackedCiphertextMarshal(t *testing.T) {
+	sk := NewTwistedSecretKey(priv)
+	p, _ := NewPacking([]uint{8, 8}, 3)
+	a, _ := p.Encrypt(rand.Reader, sk.Public(), []uint32{200, 1})
+	b, _ := p.Encrypt(rand.Reader, sk.Public(), []uint32{100, 2})
+	sum, _ := a.Add(b)
+	der, err := MarshalPacked(sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err = UnmarshalPacked(p, der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Terms() != 2 {
+		t.Fatalf("expected 2 terms, got %d", sum.Terms())
+	}
+	got, err := sum.Decrypt(sk)
+	if !errors.Is(err, ErrSlotOverflow) || !slices.Equal(got, []uint32{300, 3}) {
+		t.Fatalf("unexpected slots %v, %v", got, err)
+	}
+	// the headroom accounting survives the round trip
+	full, err := sum.Add(sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = full.Add(a); err != ErrSlotOverflow {
+		t.Fatalf("expected ErrSlotOverflow, got %v", err)
+	}
+	if _, err = NewPackedCiphertext(p, sum.Ciphertext(), 5); err != ErrSlotOverflow {
+		t.Fatalf("expected ErrSlotOverflow, got %v", err)
+	}
+	if _, err = NewPackedCiphertext(p, sum.Ciphertext(), 0); err != ErrInvalidPacking {
+		t.Fatalf("expected ErrInvalidPacking, got %v", err)
+	}
+}
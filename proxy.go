@@ -0,0 +1,141 @@
+package sm2elgamal
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+
+	"github.com/emmansun/gmsm/sm2"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// ErrInvalidReencryptionKey is returned when a re-encryption key can't be
+// created from the keys, or is nil or out of range.
+var ErrInvalidReencryptionKey = errors.New("invalid re-encryption key")
+
+// ReencryptionKey converts the ciphertexts of one key to another key in the
+// way of BBS98 proxy re-encryption, the proxy holding it learns nothing of
+// the plaintexts.
+//
+// A ciphertext (rG, raG + mG) of the key a becomes (rk*rG, raG + mG) with
+// rk = a/b, which is (r'bG, r'bG + mG) with r' = ra/b, the ciphertext of
+// the same m to the key b. So the homomorphic operations commute with the
+// re-encryption.
+//
+// The scheme is bidirectional, 1/rk converts the ciphertexts of b back to
+// a. The proxy colluding with either party learns the key of the other one,
+// a = rk*b. The second point is kept, so the re-encrypted ciphertext can be
+// linked to the original one, use [Rerandomize] with the new public key to
+// prevent it. Only the standard scheme is supported.
+type ReencryptionKey struct {
+	curve    elliptic.Curve
+	k        *big.Int
+	from, to []byte // the fingerprints of the keys
+}
+
+// NewReencryptionKey creates the re-encryption key from the key from to the
+// key to. It needs both private keys, so it is generated by the two parties
+// together or by a dealer trusted by both.
+func NewReencryptionKey(from, to *sm2.PrivateKey) (*ReencryptionKey, error) {
+	if from == nil || to == nil || from.D == nil || to.D == nil || from.Curve == nil || to.Curve == nil {
+		return nil, ErrInvalidReencryptionKey
+	}
+	if !sameCurve(from.Curve, to.Curve) {
+		return nil, ErrCurveMismatch
+	}
+	N := from.Curve.Params().N
+	k := new(big.Int).ModInverse(to.D, N)
+	if k == nil {
+		return nil, ErrInvalidReencryptionKey
+	}
+	k.Mul(k, from.D).Mod(k, N)
+	if k.Sign() == 0 {
+		return nil, ErrInvalidReencryptionKey
+	}
+	return &ReencryptionKey{
+		curve: from.Curve,
+		k:     k,
+		from:  PublicKeyFingerprint(&from.PublicKey),
+		to:    PublicKeyFingerprint(&to.PublicKey),
+	}, nil
+}
+
+// Invert returns the re-encryption key of the opposite direction.
+func (rk *ReencryptionKey) Invert() *ReencryptionKey {
+	return &ReencryptionKey{
+		curve: rk.curve,
+		k:     new(big.Int).ModInverse(rk.k, rk.curve.Params().N),
+		from:  rk.to,
+		to:    rk.from,
+	}
+}
+
+// Reencrypt converts c of the source key to the ciphertext of the same
+// plaintext to the target key, it returns ErrKeyMismatch if c is tagged
+// with another key. The plaintext type and the bound of c are kept.
+func (rk *ReencryptionKey) Reencrypt(c *Ciphertext) (*Ciphertext, error) {
+	if rk == nil || rk.k == nil {
+		return nil, ErrInvalidReencryptionKey
+	}
+	x1, y1, _, _, err := c.points()
+	if err != nil {
+		return nil, err
+	}
+	if !sameCurve(rk.curve, c.curve) {
+		return nil, ErrCurveMismatch
+	}
+	if c.scheme != 0 && c.scheme != SchemeStandard {
+		return nil, ErrSchemeMismatch
+	}
+	if c.key != nil && !bytes.Equal(c.key, rk.from) {
+		return nil, ErrKeyMismatch
+	}
+	x1, y1 = rk.curve.ScalarMult(x1, y1, rk.k.Bytes())
+	ret := *c
+	ret.c1 = marshalPoint(rk.curve, x1, y1)
+	ret.c2 = bytes.Clone(c.c2)
+	ret.key = rk.to
+	return &ret, nil
+}
+
+// MarshalReencryptionKey converts the re-encryption key to ASN.1 DER form,
+// the curve is SM2 as in [Marshal].
+//
+//	ReencryptionKey ::= SEQUENCE {
+//	  k    INTEGER,
+//	  from OCTET STRING, -- the fingerprint of the source key
+//	  to   OCTET STRING  -- the fingerprint of the target key }
+func MarshalReencryptionKey(rk *ReencryptionKey) ([]byte, error) {
+	if rk == nil || rk.k == nil {
+		return nil, ErrInvalidReencryptionKey
+	}
+	var b cryptobyte.Builder
+	b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1BigInt(rk.k)
+		b.AddASN1OctetString(rk.from)
+		b.AddASN1OctetString(rk.to)
+	})
+	return b.Bytes()
+}
+
+// UnmarshalReencryptionKey parses the re-encryption key in ASN.1 DER form.
+func UnmarshalReencryptionKey(der []byte) (*ReencryptionKey, error) {
+	var inner cryptobyte.String
+	ret := &ReencryptionKey{curve: sm2.P256(), k: new(big.Int)}
+	input := cryptobyte.String(der)
+	if !input.ReadASN1(&inner, asn1.SEQUENCE) ||
+		!input.Empty() ||
+		!inner.ReadASN1Integer(ret.k) ||
+		!inner.ReadASN1Bytes(&ret.from, asn1.OCTET_STRING) ||
+		!inner.ReadASN1Bytes(&ret.to, asn1.OCTET_STRING) ||
+		!inner.Empty() ||
+		len(ret.from) != keyFingerprintLen || len(ret.to) != keyFingerprintLen {
+		return nil, errors.New("invalid asn1 format re-encryption key")
+	}
+	if ret.k.Sign() <= 0 || ret.k.Cmp(ret.curve.Params().N) >= 0 {
+		return nil, ErrInvalidReencryptionKey
+	}
+	return ret, nil
+}
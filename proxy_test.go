@@ -0,0 +1,135 @@
+package sm2elgamal
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func TestReencrypt(t *testing.T) {
+	alice, _ := sm2.GenerateKey(rand.Reader)
+	bob, _ := sm2.GenerateKey(rand.Reader)
+	rk, err := NewReencryptionKey(alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c1, _ := EncryptInt32(rand.Reader, &alice.PublicKey, 1000)
+	c2, _ := EncryptInt32(rand.Reader, &alice.PublicKey, -1500)
+	sum, _ := Add(c1, c2)
+
+	// re-encrypting the sum is the same as summing the re-encrypted ciphertexts
+	r1, err := rk.Reencrypt(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, _ := rk.Reencrypt(c2)
+	rsum, err := Add(r1, r2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r3, _ := rk.Reencrypt(sum)
+	for _, c := range []*Ciphertext{rsum, r3} {
+		if v, err := DecryptInt32(bob, c); err != nil || v != -500 {
+			t.Fatalf("expected -500, got %v, %v", v, err)
+		}
+		if _, err = DecryptInt32(alice, c); err != ErrKeyMismatch {
+			t.Fatalf("expected ErrKeyMismatch, got %v", err)
+		}
+	}
+	if v, err := DecryptInt32(bob, r1); err != nil || v != 1000 {
+		t.Fatalf("expected 1000, got %v, %v", v, err)
+	}
+
+	// rerandomized for unlinkability
+	rr, err := Rerandomize(rand.Reader, &bob.PublicKey, r1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := DecryptInt32(bob, rr); err != nil || v != 1000 {
+		t.Fatalf("expected 1000, got %v, %v", v, err)
+	}
+
+	// back to alice
+	back, err := rk.Invert().Reencrypt(r1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := DecryptInt32(alice, back); err != nil || v != 1000 {
+		t.Fatalf("expected 1000, got %v, %v", v, err)
+	}
+
+	if _, err = rk.Reencrypt(r1); err != ErrKeyMismatch {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+	tc, _ := priv.EncryptUint32(rand.Reader, 1)
+	if _, err = rk.Reencrypt(tc); err != ErrSchemeMismatch {
+		t.Fatalf("expected ErrSchemeMismatch, got %v", err)
+	}
+}
+
+func TestReencryptionKeyMarshal(t *testing.T) {
+	alice, _ := sm2.GenerateKey(rand.Reader)
+	bob, _ := sm2.GenerateKey(rand.Reader)
+	rk, err := NewReencryptionKey(alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := MarshalReencryptionKey(rk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rk2, err := UnmarshalReencryptionKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rk2.k.Cmp(rk.k) != 0 || string(rk2.from) != string(rk.from) || string(rk2.to) != string(rk.to) {
+		t.Fatal("re-encryption key mismatch")
+	}
+	c, _ := EncryptUint32(rand.Reader, &alice.PublicKey, 42)
+	r, err := rk2.Reencrypt(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := DecryptUint32(bob, r); err != nil || v != 42 {
+		t.Fatalf("expected 42, got %v, %v", v, err)
+	}
+
+	if _, err = UnmarshalReencryptionKey(der[:len(der)-1]); err == nil {
+		t.Fatal("should reject truncated key")
+	}
+	zero := &ReencryptionKey{k: new(big.Int), from: rk.from, to: rk.to}
+	if der, err = MarshalReencryptionKey(zero); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = UnmarshalReencryptionKey(der); err != ErrInvalidReencryptionKey {
+		t.Fatalf("expected ErrInvalidReencryptionKey, got %v", err)
+	}
+}
+
+func TestInvalidReencryptionKey(t *testing.T) {
+	alice, _ := sm2.GenerateKey(rand.Reader)
+	zero := &sm2.PrivateKey{}
+	zero.Curve = alice.Curve
+	zero.D = new(big.Int)
+	for _, c := range []struct{ from, to *sm2.PrivateKey }{
+		{nil, alice},
+		{alice, nil},
+		{alice, &sm2.PrivateKey{}},
+		{alice, zero},
+		{zero, alice},
+	} {
+		if _, err := NewReencryptionKey(c.from, c.to); err != ErrInvalidReencryptionKey {
+			t.Fatalf("expected ErrInvalidReencryptionKey, got %v", err)
+		}
+	}
+	var rk *ReencryptionKey
+	c, _ := EncryptUint32(rand.Reader, &alice.PublicKey, 1)
+	if _, err := rk.Reencrypt(c); err != ErrInvalidReencryptionKey {
+		t.Fatalf("expected ErrInvalidReencryptionKey, got %v", err)
+	}
+	if _, err := MarshalReencryptionKey(nil); err != ErrInvalidReencryptionKey {
+		t.Fatalf("expected ErrInvalidReencryptionKey, got %v", err)
+	}
+}
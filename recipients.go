@@ -0,0 +1,100 @@
+package sm2elgamal
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// ErrNoRecipient is returned when there is no public key to encrypt to.
+var ErrNoRecipient = errors.New("no recipient")
+
+// RecipientsCiphertext is the encryption of one value to several public
+// keys with the same randomness r, that is rG shared by all the recipients
+// and rP_i + mG for each of them.
+//
+// Reusing r for different keys is as secure as encrypting separately, the
+// shared rG reveals nothing more than any one of the ciphertexts does.
+type RecipientsCiphertext struct {
+	curve elliptic.Curve
+	c1    []byte
+	c2    [][]byte
+	keys  [][]byte // the fingerprints of the recipients
+	ptype PlaintextType
+}
+
+// EncryptMulti encrypts m of type T to each of the public keys, the keys
+// must be of the same curve. The ciphertext of each recipient is the same
+// as [EncryptUint32] or [EncryptInt32] would return with the same
+// randomness, see [RecipientsCiphertext.Recipient].
+func EncryptMulti[T Integer](random io.Reader, pubs []*ecdsa.PublicKey, m T) (*RecipientsCiphertext, error) {
+	if len(pubs) == 0 {
+		return nil, ErrNoRecipient
+	}
+	for _, pub := range pubs {
+		if pub == nil || pub.Curve == nil || !sameCurve(pubs[0].Curve, pub.Curve) {
+			return nil, ErrCurveMismatch
+		}
+	}
+	curve := pubs[0].Curve
+	r, err := randFieldElement(curve, random)
+	if err != nil {
+		return nil, err
+	}
+	mv := new(big.Int).Mod(integerToBig(m), curve.Params().N)
+	ret := &RecipientsCiphertext{
+		curve: curve,
+		c2:    make([][]byte, len(pubs)),
+		keys:  make([][]byte, len(pubs)),
+		ptype: plaintextTypeOf[T](),
+	}
+	for i, pub := range pubs {
+		c := encrypt(pub, mv, r)
+		if i == 0 {
+			ret.c1 = c.c1
+		}
+		ret.c2[i], ret.keys[i] = c.c2, c.key
+	}
+	return ret, nil
+}
+
+// Len returns the number of recipients.
+func (c *RecipientsCiphertext) Len() int {
+	return len(c.c2)
+}
+
+// Recipient returns the ciphertext of recipient i, in the order of the
+// public keys passed to [EncryptMulti]. It returns ErrInvalidCiphertext if
+// there is no recipient i.
+func (c *RecipientsCiphertext) Recipient(i int) (*Ciphertext, error) {
+	if c == nil || i < 0 || i >= len(c.c2) {
+		return nil, ErrInvalidCiphertext
+	}
+	return &Ciphertext{
+		curve: c.curve,
+		c1:    bytes.Clone(c.c1),
+		c2:    bytes.Clone(c.c2[i]),
+		tags:  tags{scheme: SchemeStandard, ptype: c.ptype, key: c.keys[i]},
+	}, nil
+}
+
+// For returns the ciphertext of the recipient of pub, it returns
+// ErrKeyMismatch if pub is nil or not one of the recipients.
+func (c *RecipientsCiphertext) For(pub *ecdsa.PublicKey) (*Ciphertext, error) {
+	if c == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	if pub == nil || pub.Curve == nil {
+		return nil, ErrKeyMismatch
+	}
+	fp := PublicKeyFingerprint(pub)
+	for i, key := range c.keys {
+		if bytes.Equal(key, fp) {
+			return c.Recipient(i)
+		}
+	}
+	return nil, ErrKeyMismatch
+}
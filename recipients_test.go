@@ -0,0 +1,89 @@
+package sm2elgamal
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func TestEncryptMulti(t *testing.T) {
+	var keys []*sm2.PrivateKey
+	var pubs []*ecdsa.PublicKey
+	for i := 0; i < 3; i++ {
+		k, _ := sm2.GenerateKey(rand.Reader)
+		keys = append(keys, k)
+		pubs = append(pubs, &k.PublicKey)
+	}
+	c, err := EncryptMulti(rand.Reader, pubs, int32(-100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != len(pubs) {
+		t.Fatalf("expected %d recipients, got %d", len(pubs), c.Len())
+	}
+	for i, k := range keys {
+		ci, err := c.For(&k.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// it works with the other ciphertexts of the key
+		other, _ := EncryptInt32(rand.Reader, &k.PublicKey, 30)
+		if ci, err = Add(ci, other); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := DecryptInt32(k, ci); err != nil || v != -70 {
+			t.Fatalf("recipient %d: expected -70, got %v, %v", i, v, err)
+		}
+		if _, err = DecryptInt32(keys[(i+1)%len(keys)], ci); err != ErrKeyMismatch {
+			t.Fatalf("expected ErrKeyMismatch, got %v", err)
+		}
+	}
+	stranger, _ := sm2.GenerateKey(rand.Reader)
+	if _, err = c.For(&stranger.PublicKey); err != ErrKeyMismatch {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+	if _, err = c.For(nil); err != ErrKeyMismatch {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+	if _, err = c.For(&ecdsa.PublicKey{}); err != ErrKeyMismatch {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+	if _, err = c.Recipient(len(pubs)); err != ErrInvalidCiphertext {
+		t.Fatalf("expected ErrInvalidCiphertext, got %v", err)
+	}
+	if _, err = EncryptMulti(rand.Reader, nil, uint32(1)); err != ErrNoRecipient {
+		t.Fatalf("expected ErrNoRecipient, got %v", err)
+	}
+	if _, err = EncryptMulti(rand.Reader, append([]*ecdsa.PublicKey{nil}, pubs...), uint32(1)); err != ErrCurveMismatch {
+		t.Fatalf("expected ErrCurveMismatch, got %v", err)
+	}
+}
+
+func TestEncryptMultiSameAsSeparate(t *testing.T) {
+	var pubs []*ecdsa.PublicKey
+	for i := 0; i < 3; i++ {
+		k, _ := sm2.GenerateKey(rand.Reader)
+		pubs = append(pubs, &k.PublicKey)
+	}
+	seed := make([]byte, 1024)
+	rand.Read(seed)
+	c, err := EncryptMulti(bytes.NewReader(seed), pubs, uint32(12345))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, pub := range pubs {
+		want, _ := EncryptUint32(bytes.NewReader(seed), pub, 12345)
+		wantDER, _ := Marshal(want)
+		ci, err := c.Recipient(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := Marshal(ci)
+		if !bytes.Equal(got, wantDER) {
+			t.Fatalf("recipient %d: the ciphertext differs from separate encryption", i)
+		}
+	}
+}
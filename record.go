@@ -0,0 +1,430 @@
+package sm2elgamal
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/emmansun/gmsm/sm2"
+	"github.com/emmansun/gmsm/sm3"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// The record mode encrypts a record of k values with one randomness r
+// to k slot keys P_i = d_i*G, as (rG, rP_1 + m_1*G, ..., rP_k + m_k*G), so
+// the record takes k+1 points instead of 2k.
+//
+// The slots can't share one key with k hashed generators H_i, that is
+// rH_i + m_i*G: nobody knows the discrete logarithm of H_i, so rH_i can't be
+// computed from rG to decrypt. Neither can the slot keys be derived from the
+// public key alone like P + h_i*G, since then (C_i - C_j) - (h_i - h_j)rG =
+// (m_i - m_j)G would leak the differences of the values to anyone. So the
+// slot keys are derived from the private key, d_i = H(d, i), and the k slot
+// public keys are published.
+
+// ErrInvalidSlots is returned when a record key has no slot.
+var ErrInvalidSlots = errors.New("invalid number of slots")
+
+// recordKeyLabel is the domain separation label of the slot key derivation.
+const recordKeyLabel = "sm2elgamal record slot key"
+
+// RecordPrivateKey is the private key of the record mode, it has one
+// key per slot.
+type RecordPrivateKey struct {
+	slots []*sm2PrivateKey
+}
+
+// RecordPublicKey is the public key of the record mode, it has one key per slot.
+type RecordPublicKey struct {
+	slots []*ecdsa.PublicKey
+}
+
+// DeriveRecordKey derives the k slot keys of the record mode from priv.
+func DeriveRecordKey(priv *sm2.PrivateKey, k int) (*RecordPrivateKey, error) {
+	if k < 1 {
+		return nil, ErrInvalidSlots
+	}
+	curve := priv.Curve
+	N := curve.Params().N
+	d := make([]byte, (N.BitLen()+7)/8)
+	priv.D.FillBytes(d)
+	ret := &RecordPrivateKey{slots: make([]*sm2PrivateKey, k)}
+	for i := range ret.slots {
+		// 64 bytes of SM3 output reduced modulo N, the bias is negligible
+		var wide []byte
+		for counter := byte(0); counter < 2; counter++ {
+			h := sm3.New()
+			h.Write([]byte(recordKeyLabel))
+			h.Write(d)
+			h.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+			h.Write([]byte{counter})
+			wide = h.Sum(wide)
+		}
+		di := new(big.Int).Mod(new(big.Int).SetBytes(wide), N)
+		if di.Sign() == 0 {
+			return nil, errors.New("invalid derived key")
+		}
+		slot := &sm2PrivateKey{}
+		slot.Curve, slot.D = curve, di
+		slot.PublicKey.X, slot.PublicKey.Y = curve.ScalarBaseMult(di.Bytes())
+		ret.slots[i] = slot
+	}
+	return ret, nil
+}
+
+// Len returns the number of slots.
+func (priv *RecordPrivateKey) Len() int {
+	return len(priv.slots)
+}
+
+// Slot returns the key of slot i, it decrypts the ciphertext of [RecordCiphertext.Slot].
+func (priv *RecordPrivateKey) Slot(i int) DHKey {
+	return priv.slots[i]
+}
+
+// Public returns the public key.
+func (priv *RecordPrivateKey) Public() *RecordPublicKey {
+	pub := &RecordPublicKey{slots: make([]*ecdsa.PublicKey, len(priv.slots))}
+	for i, slot := range priv.slots {
+		pub.slots[i] = &slot.PublicKey
+	}
+	return pub
+}
+
+// NewRecordPublicKey creates a public key of the slot keys.
+func NewRecordPublicKey(keys []*ecdsa.PublicKey) (*RecordPublicKey, error) {
+	if len(keys) == 0 {
+		return nil, ErrInvalidSlots
+	}
+	for _, k := range keys {
+		if k == nil || !sameCurve(k.Curve, keys[0].Curve) {
+			return nil, ErrCurveMismatch
+		}
+	}
+	return &RecordPublicKey{slots: append([]*ecdsa.PublicKey(nil), keys...)}, nil
+}
+
+// Len returns the number of slots.
+func (pub *RecordPublicKey) Len() int {
+	return len(pub.slots)
+}
+
+// Keys returns the slot keys.
+func (pub *RecordPublicKey) Keys() []*ecdsa.PublicKey {
+	return append([]*ecdsa.PublicKey(nil), pub.slots...)
+}
+
+// RecordCiphertext is the ciphertext of a record in the record mode.
+type RecordCiphertext struct {
+	curve elliptic.Curve
+	c1    []byte
+	c2    [][]byte
+	ptype PlaintextType
+	keys  []byte // the fingerprints of the slot keys one after another, nil if unknown
+}
+
+// EncryptRecordUint32 encrypts the values of a record, one per slot.
+func EncryptRecordUint32(random io.Reader, pub *RecordPublicKey, values []uint32) (*RecordCiphertext, error) {
+	ms := make([]*big.Int, len(values))
+	for i, m := range values {
+		ms[i] = new(big.Int).SetUint64(uint64(m))
+	}
+	return encryptRecord(random, pub, ms, PlaintextUint32)
+}
+
+// EncryptRecordInt32 encrypts the values of a record, one per slot.
+func EncryptRecordInt32(random io.Reader, pub *RecordPublicKey, values []int32) (*RecordCiphertext, error) {
+	ms := make([]*big.Int, len(values))
+	for i, m := range values {
+		ms[i] = big.NewInt(int64(m))
+	}
+	return encryptRecord(random, pub, ms, PlaintextInt32)
+}
+
+func encryptRecord(random io.Reader, pub *RecordPublicKey, ms []*big.Int, ptype PlaintextType) (*RecordCiphertext, error) {
+	if pub == nil || len(pub.slots) == 0 {
+		return nil, ErrInvalidSlots
+	}
+	if len(ms) != len(pub.slots) {
+		return nil, ErrLengthMismatch
+	}
+	curve := pub.slots[0].Curve
+	N := curve.Params().N
+	r, err := randFieldElement(curve, random)
+	if err != nil {
+		return nil, err
+	}
+	x1, y1 := curve.ScalarBaseMult(r.Bytes())
+	ret := &RecordCiphertext{curve: curve, c1: marshalPoint(curve, x1, y1), c2: make([][]byte, len(ms)), ptype: ptype}
+	for _, slot := range pub.slots {
+		ret.keys = append(ret.keys, PublicKeyFingerprint(slot)...)
+	}
+	for i, m := range ms {
+		x2, y2 := curve.ScalarMult(pub.slots[i].X, pub.slots[i].Y, r.Bytes())
+		mx, my := curve.ScalarBaseMult(new(big.Int).Mod(m, N).Bytes())
+		x2, y2 = curve.Add(x2, y2, mx, my)
+		ret.c2[i] = marshalPoint(curve, x2, y2)
+	}
+	return ret, nil
+}
+
+// Len returns the number of slots.
+func (c *RecordCiphertext) Len() int {
+	return len(c.c2)
+}
+
+// Slot returns the ciphertext of slot i, which is decrypted by the key of
+// slot i, and can be used with the other ciphertexts of that key. It
+// returns ErrInvalidCiphertext if there is no slot i.
+func (c *RecordCiphertext) Slot(i int) (*Ciphertext, error) {
+	if c == nil || i < 0 || i >= len(c.c2) {
+		return nil, ErrInvalidCiphertext
+	}
+	ret := &Ciphertext{
+		curve: c.curve,
+		c1:    bytes.Clone(c.c1),
+		c2:    bytes.Clone(c.c2[i]),
+		tags:  tags{scheme: SchemeStandard, ptype: c.ptype},
+	}
+	if c.keys != nil {
+		ret.key = c.keys[i*keyFingerprintLen : (i+1)*keyFingerprintLen]
+	}
+	return ret, nil
+}
+
+// points returns the points of the ciphertext.
+func (c *RecordCiphertext) points() (x1, y1 *big.Int, c2 [][2]*big.Int, err error) {
+	if c == nil || c.curve == nil {
+		return nil, nil, nil, ErrInvalidCiphertext
+	}
+	if x1, y1, err = unmarshalPoint(c.curve, c.c1); err != nil {
+		return nil, nil, nil, err
+	}
+	c2 = make([][2]*big.Int, len(c.c2))
+	for i, p := range c.c2 {
+		x, y, err := unmarshalPoint(c.curve, p)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		c2[i] = [2]*big.Int{x, y}
+	}
+	return x1, y1, c2, nil
+}
+
+// combine returns c + other, or c - other if negate is true.
+func (c *RecordCiphertext) combine(other *RecordCiphertext, negate bool) (*RecordCiphertext, error) {
+	x1, y1, c2, err := c.points()
+	if err != nil {
+		return nil, err
+	}
+	ox1, oy1, oc2, err := other.points()
+	if err != nil {
+		return nil, err
+	}
+	if !sameCurve(c.curve, other.curve) {
+		return nil, ErrCurveMismatch
+	}
+	if len(c2) != len(oc2) {
+		return nil, ErrLengthMismatch
+	}
+	ptype := c.ptype
+	if ptype == 0 {
+		ptype = other.ptype
+	} else if other.ptype != 0 && other.ptype != ptype {
+		return nil, ErrPlaintextTypeMismatch
+	}
+	if negate && ptype == PlaintextUint32 {
+		// the difference may be negative
+		ptype = 0
+	}
+	keys := c.keys
+	if keys == nil {
+		keys = other.keys
+	} else if other.keys != nil && !bytes.Equal(keys, other.keys) {
+		return nil, ErrKeyMismatch
+	}
+	P := c.curve.Params().P
+	neg := func(y *big.Int) *big.Int {
+		if !negate || y.Sign() == 0 {
+			return y
+		}
+		return new(big.Int).Sub(P, y)
+	}
+	x1, y1 = c.curve.Add(x1, y1, ox1, neg(oy1))
+	ret := &RecordCiphertext{curve: c.curve, c1: marshalPoint(c.curve, x1, y1), c2: make([][]byte, len(c2)), ptype: ptype, keys: keys}
+	for i := range c2 {
+		x, y := c.curve.Add(c2[i][0], c2[i][1], oc2[i][0], neg(oc2[i][1]))
+		ret.c2[i] = marshalPoint(c.curve, x, y)
+	}
+	return ret, nil
+}
+
+// Add returns c + other slot by slot.
+func (c *RecordCiphertext) Add(other *RecordCiphertext) (*RecordCiphertext, error) {
+	return c.combine(other, false)
+}
+
+// Sub returns c - other slot by slot.
+func (c *RecordCiphertext) Sub(other *RecordCiphertext) (*RecordCiphertext, error) {
+	return c.combine(other, true)
+}
+
+// ScalarMult returns k * c, all the slots are multiplied by k.
+func (c *RecordCiphertext) ScalarMult(k *big.Int) (*RecordCiphertext, error) {
+	x1, y1, c2, err := c.points()
+	if err != nil {
+		return nil, err
+	}
+	scalar := new(big.Int).Mod(k, c.curve.Params().N).Bytes()
+	x1, y1 = c.curve.ScalarMult(x1, y1, scalar)
+	ret := &RecordCiphertext{curve: c.curve, c1: marshalPoint(c.curve, x1, y1), c2: make([][]byte, len(c2)), ptype: c.ptype, keys: c.keys}
+	for i := range c2 {
+		x, y := c.curve.ScalarMult(c2[i][0], c2[i][1], scalar)
+		ret.c2[i] = marshalPoint(c.curve, x, y)
+	}
+	return ret, nil
+}
+
+// AddPlain returns c with the plaintext k added to slot i.
+func (c *RecordCiphertext) AddPlain(i int, k *big.Int) (*RecordCiphertext, error) {
+	slot, err := c.Slot(i)
+	if err != nil {
+		return nil, err
+	}
+	if slot, err = AddPlain(slot, k); err != nil {
+		return nil, err
+	}
+	ret := *c
+	ret.c2 = append([][]byte(nil), c.c2...)
+	ret.c2[i] = slot.c2
+	return &ret, nil
+}
+
+// DecryptUint32 decrypts all the slots to uint32, it returns ErrLengthMismatch if
+// priv is nil or has another number of slots.
+func (c *RecordCiphertext) DecryptUint32(priv *RecordPrivateKey) ([]uint32, error) {
+	if c == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	if priv == nil || len(priv.slots) != len(c.c2) {
+		return nil, ErrLengthMismatch
+	}
+	ret := make([]uint32, len(c.c2))
+	for i := range c.c2 {
+		slot, err := c.Slot(i)
+		if err != nil {
+			return nil, err
+		}
+		m, err := decryptUint32(priv.slots[i], slot)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = m
+	}
+	return ret, nil
+}
+
+// DecryptInt32 decrypts all the slots to int32, it returns ErrLengthMismatch if
+// priv is nil or has another number of slots.
+func (c *RecordCiphertext) DecryptInt32(priv *RecordPrivateKey) ([]int32, error) {
+	if c == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	if priv == nil || len(priv.slots) != len(c.c2) {
+		return nil, ErrLengthMismatch
+	}
+	ret := make([]int32, len(c.c2))
+	for i := range c.c2 {
+		slot, err := c.Slot(i)
+		if err != nil {
+			return nil, err
+		}
+		m, err := decryptInt32(priv.slots[i], slot)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = m
+	}
+	return ret, nil
+}
+
+// MarshalRecord converts the record ciphertext to ASN.1 DER form.
+//
+//	RecordCiphertext ::= SEQUENCE {
+//	  c1            OCTET STRING,
+//	  c2            SEQUENCE OF OCTET STRING,
+//	  plaintextType [1] INTEGER OPTIONAL,
+//	  slotKeys      [2] OCTET STRING OPTIONAL -- the fingerprints of the slot keys }
+func MarshalRecord(c *RecordCiphertext) ([]byte, error) {
+	if _, _, _, err := c.points(); err != nil {
+		return nil, err
+	}
+	var b cryptobyte.Builder
+	b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1OctetString(c.c1)
+		b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			for _, p := range c.c2 {
+				b.AddASN1OctetString(p)
+			}
+		})
+		if c.ptype != 0 {
+			b.AddASN1(plaintextTypeTag, func(b *cryptobyte.Builder) {
+				b.AddASN1Uint64(uint64(c.ptype))
+			})
+		}
+		if c.keys != nil {
+			b.AddASN1(keyFingerprintTag, func(b *cryptobyte.Builder) {
+				b.AddASN1OctetString(c.keys)
+			})
+		}
+	})
+	return b.Bytes()
+}
+
+// UnmarshalRecord parses the record ciphertext in ASN.1 DER form.
+func UnmarshalRecord(der []byte) (*RecordCiphertext, error) {
+	var (
+		inner, slots cryptobyte.String
+		ptype        int
+		keys         []byte
+		hasKeys      bool
+	)
+	ret := &RecordCiphertext{curve: sm2.P256()}
+	input := cryptobyte.String(der)
+	if !input.ReadASN1(&inner, asn1.SEQUENCE) ||
+		!input.Empty() ||
+		!inner.ReadASN1Bytes(&ret.c1, asn1.OCTET_STRING) ||
+		!inner.ReadASN1(&slots, asn1.SEQUENCE) ||
+		!inner.ReadOptionalASN1Integer(&ptype, plaintextTypeTag, 0) ||
+		!inner.ReadOptionalASN1OctetString(&keys, &hasKeys, keyFingerprintTag) ||
+		!inner.Empty() ||
+		ptype < 0 || ptype > int(PlaintextRange) {
+		return nil, errors.New("invalid asn1 format record ciphertext")
+	}
+	for !slots.Empty() {
+		var p []byte
+		if !slots.ReadASN1Bytes(&p, asn1.OCTET_STRING) {
+			return nil, errors.New("invalid asn1 format record ciphertext")
+		}
+		ret.c2 = append(ret.c2, p)
+	}
+	if len(ret.c2) == 0 {
+		return nil, errors.New("invalid asn1 format record ciphertext")
+	}
+	if hasKeys {
+		if len(keys) != keyFingerprintLen*len(ret.c2) {
+			return nil, errors.New("invalid asn1 format record ciphertext")
+		}
+		ret.keys = keys
+	}
+	ret.ptype = PlaintextType(ptype)
+	if _, _, _, err := ret.points(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
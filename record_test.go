@@ -0,0 +1,159 @@
+package sm2elgamal
+
+import (
+	"crypto/rand"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func TestRecordCiphertext(t *testing.T) {
+	std, _ := sm2.GenerateKey(rand.Reader)
+	priv, err := DeriveRecordKey(std, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := DeriveRecordKey(std, 4)
+	for i := 0; i < priv.Len(); i++ {
+		if !priv.Public().Keys()[i].Equal(again.Public().Keys()[i]) {
+			t.Fatal("derivation should be deterministic")
+		}
+		for j := 0; j < i; j++ {
+			if priv.Public().Keys()[i].Equal(priv.Public().Keys()[j]) {
+				t.Fatal("slot keys should differ")
+			}
+		}
+	}
+	pub, err := NewRecordPublicKey(priv.Public().Keys())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := EncryptRecordInt32(rand.Reader, pub, []int32{1, -2, 3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := EncryptRecordInt32(rand.Reader, pub, []int32{10, 20, 30, -40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := a.Add(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum, err = sum.ScalarMult(big.NewInt(2)); err != nil {
+		t.Fatal(err)
+	}
+	if sum, err = sum.AddPlain(1, big.NewInt(-6)); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := sum.DecryptInt32(priv); err != nil || !slices.Equal(v, []int32{22, 30, 66, -72}) {
+		t.Fatalf("unexpected values %v, %v", v, err)
+	}
+	diff, err := b.Sub(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := diff.DecryptInt32(priv); err != nil || !slices.Equal(v, []int32{9, 22, 27, -44}) {
+		t.Fatalf("unexpected values %v, %v", v, err)
+	}
+
+	// a slot works with the ordinary ciphertexts of the slot key
+	slot, err := diff.Slot(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := EncryptInt32(rand.Reader, pub.Keys()[2], 3)
+	if slot, err = Add(slot, c); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := DecryptInt32DH(priv.Slot(2), slot); err != nil || v != 30 {
+		t.Fatalf("expected 30, got %v, %v", v, err)
+	}
+	if _, err = DecryptInt32DH(priv.Slot(1), slot); err != ErrKeyMismatch {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+	if _, err = diff.Slot(4); err != ErrInvalidCiphertext {
+		t.Fatalf("expected ErrInvalidCiphertext, got %v", err)
+	}
+	if _, err = diff.Slot(-1); err != ErrInvalidCiphertext {
+		t.Fatalf("expected ErrInvalidCiphertext, got %v", err)
+	}
+	// the slot doesn't share memory with the record ciphertext
+	s0, _ := diff.Slot(0)
+	s0.c1[1] ^= 0xff
+	if v, err := diff.DecryptInt32(priv); err != nil || v[0] != 9 {
+		t.Fatalf("expected 9, got %v, %v", v, err)
+	}
+
+	u, _ := EncryptRecordUint32(rand.Reader, pub, []uint32{1, 2, 3, 4})
+	if _, err = a.Add(u); err != ErrPlaintextTypeMismatch {
+		t.Fatalf("expected ErrPlaintextTypeMismatch, got %v", err)
+	}
+	if _, err = EncryptRecordUint32(rand.Reader, pub, []uint32{1}); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+	for _, pub := range []*RecordPublicKey{nil, {}} {
+		if _, err = EncryptRecordInt32(rand.Reader, pub, nil); err != ErrInvalidSlots {
+			t.Fatalf("expected ErrInvalidSlots, got %v", err)
+		}
+		if _, err = EncryptRecordUint32(rand.Reader, pub, []uint32{1}); err != ErrInvalidSlots {
+			t.Fatalf("expected ErrInvalidSlots, got %v", err)
+		}
+	}
+	if _, err = a.DecryptInt32(nil); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+	if _, err = u.DecryptUint32(nil); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+	var none *RecordCiphertext
+	if _, err = none.DecryptUint32(priv); err != ErrInvalidCiphertext {
+		t.Fatalf("expected ErrInvalidCiphertext, got %v", err)
+	}
+	if _, err = none.DecryptInt32(priv); err != ErrInvalidCiphertext {
+		t.Fatalf("expected ErrInvalidCiphertext, got %v", err)
+	}
+}
+
+func TestRecordCiphertextMarshal(t *testing.T) {
+	std, _ := sm2.GenerateKey(rand.Reader)
+	priv, _ := DeriveRecordKey(std, 8)
+	values := []uint32{0, 1, 2, 3, 4, 5, 6, 0xffffffff}
+	c, err := EncryptRecordUint32(rand.Reader, priv.Public(), values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := MarshalRecord(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	single, _ := EncryptUint32(rand.Reader, &std.PublicKey, 1)
+	singleDER, _ := Marshal(single)
+	if len(der)*10 > len(singleDER)*len(values)*6 {
+		t.Fatalf("%d bytes is too large compared with %d bytes per value", len(der), len(singleDER))
+	}
+	c2, err := UnmarshalRecord(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c2.Len() != len(values) {
+		t.Fatalf("expected %d slots, got %d", len(values), c2.Len())
+	}
+	if v, err := c2.DecryptUint32(priv); err != nil || !slices.Equal(v, values) {
+		t.Fatalf("unexpected values %v, %v", v, err)
+	}
+	if _, err = c2.DecryptInt32(priv); err != ErrPlaintextTypeMismatch {
+		t.Fatalf("expected ErrPlaintextTypeMismatch, got %v", err)
+	}
+	otherStd, _ := sm2.GenerateKey(rand.Reader)
+	other, _ := DeriveRecordKey(otherStd, 8)
+	if _, err = c2.DecryptUint32(other); err != ErrKeyMismatch {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+	if _, err = UnmarshalRecord(der[:len(der)-3]); err == nil {
+		t.Fatal("should reject truncated record ciphertext")
+	}
+}
@@ -0,0 +1,202 @@
+package sm2elgamal
+
+import (
+	"crypto/ecdsa"
+	"io"
+	"math/big"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+// Scheme is an additively homomorphic encryption scheme, the standard and the
+// Twisted EC-ElGamal schemes both implement it, so that the application code
+// can switch between them.
+type Scheme interface {
+	// GenerateKey generates a secret key of the scheme.
+	GenerateKey(rand io.Reader) (SecretKey, error)
+	// NewPublicKey wraps a public key of the scheme.
+	NewPublicKey(pub *ecdsa.PublicKey) PublicKey
+
+	// Add returns c1 + c2.
+	Add(c1, c2 *Ciphertext) (*Ciphertext, error)
+	// Sub returns c1 - c2.
+	Sub(c1, c2 *Ciphertext) (*Ciphertext, error)
+	// Neg returns -c.
+	Neg(c *Ciphertext) (*Ciphertext, error)
+	// AddPlain returns c + k.
+	AddPlain(c *Ciphertext, k *big.Int) (*Ciphertext, error)
+	// ScalarMult returns k * c.
+	ScalarMult(c *Ciphertext, k *big.Int) (*Ciphertext, error)
+}
+
+// PublicKey is a public key of a [Scheme].
+type PublicKey interface {
+	// Scheme returns the scheme of the key.
+	Scheme() Scheme
+	// Key returns the underlying public key.
+	Key() *ecdsa.PublicKey
+	// Encrypt encrypts m, m is reduced modulo N so it can be negative.
+	Encrypt(rand io.Reader, m *big.Int) (*Ciphertext, error)
+	EncryptUint32(rand io.Reader, m uint32) (*Ciphertext, error)
+	EncryptInt32(rand io.Reader, m int32) (*Ciphertext, error)
+	// Rerandomize returns a fresh ciphertext of the same plaintext as c.
+	Rerandomize(rand io.Reader, c *Ciphertext) (*Ciphertext, error)
+}
+
+// SecretKey is a secret key of a [Scheme].
+type SecretKey interface {
+	DHKey
+	// Public returns the public key of the secret key.
+	Public() PublicKey
+	DecryptUint32(c *Ciphertext) (uint32, error)
+	DecryptInt32(c *Ciphertext) (int32, error)
+}
+
+// homomorphic implements the homomorphic operations of [Scheme], they are the
+// same for both schemes.
+type homomorphic struct{}
+
+func (homomorphic) Add(c1, c2 *Ciphertext) (*Ciphertext, error) { return Add(c1, c2) }
+func (homomorphic) Sub(c1, c2 *Ciphertext) (*Ciphertext, error) { return Sub(c1, c2) }
+func (homomorphic) Neg(c *Ciphertext) (*Ciphertext, error)      { return Neg(c) }
+func (homomorphic) AddPlain(c *Ciphertext, k *big.Int) (*Ciphertext, error) {
+	return AddPlain(c, k)
+}
+func (homomorphic) ScalarMult(c *Ciphertext, k *big.Int) (*Ciphertext, error) {
+	return ScalarMult(c, k)
+}
+
+type standardScheme struct {
+	homomorphic
+}
+
+// NewStandardScheme returns the standard EC-ElGamal scheme.
+func NewStandardScheme() Scheme {
+	return standardScheme{}
+}
+
+func (s standardScheme) GenerateKey(rand io.Reader) (SecretKey, error) {
+	priv, err := sm2.GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+	return NewStandardSecretKey(priv), nil
+}
+
+func (s standardScheme) NewPublicKey(pub *ecdsa.PublicKey) PublicKey {
+	return standardPublicKey{pub}
+}
+
+type standardPublicKey struct {
+	pub *ecdsa.PublicKey
+}
+
+func (k standardPublicKey) Scheme() Scheme        { return standardScheme{} }
+func (k standardPublicKey) Key() *ecdsa.PublicKey { return k.pub }
+
+func (k standardPublicKey) Encrypt(rand io.Reader, m *big.Int) (*Ciphertext, error) {
+	r, err := randFieldElement(k.pub.Curve, rand)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptWithNonce(k.pub, m, r)
+}
+
+func (k standardPublicKey) EncryptUint32(rand io.Reader, m uint32) (*Ciphertext, error) {
+	return EncryptUint32(rand, k.pub, m)
+}
+
+func (k standardPublicKey) EncryptInt32(rand io.Reader, m int32) (*Ciphertext, error) {
+	return EncryptInt32(rand, k.pub, m)
+}
+
+func (k standardPublicKey) Rerandomize(rand io.Reader, c *Ciphertext) (*Ciphertext, error) {
+	return Rerandomize(rand, k.pub, c)
+}
+
+type standardSecretKey struct {
+	*sm2PrivateKey
+	priv *sm2.PrivateKey
+}
+
+// NewStandardSecretKey returns the [SecretKey] of an SM2 private key.
+func NewStandardSecretKey(priv *sm2.PrivateKey) SecretKey {
+	return standardSecretKey{newPrivateKey(priv), priv}
+}
+
+func (k standardSecretKey) Public() PublicKey {
+	return standardPublicKey{&k.priv.PublicKey}
+}
+
+func (k standardSecretKey) DecryptUint32(c *Ciphertext) (uint32, error) {
+	return DecryptUint32(k.priv, c)
+}
+
+func (k standardSecretKey) DecryptInt32(c *Ciphertext) (int32, error) {
+	return DecryptInt32(k.priv, c)
+}
+
+type twistedScheme struct {
+	homomorphic
+	te *TwistedElgamal
+}
+
+// NewTwistedScheme returns the Twisted EC-ElGamal scheme of the context te.
+func NewTwistedScheme(te *TwistedElgamal) Scheme {
+	return twistedScheme{te: te}
+}
+
+func (s twistedScheme) GenerateKey(rand io.Reader) (SecretKey, error) {
+	priv, err := s.te.GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+	return twistedSecretKey{priv, s.te}, nil
+}
+
+func (s twistedScheme) NewPublicKey(pub *ecdsa.PublicKey) PublicKey {
+	return twistedPublicKey{s.te, pub}
+}
+
+type twistedPublicKey struct {
+	te  *TwistedElgamal
+	pub *ecdsa.PublicKey
+}
+
+func (k twistedPublicKey) Scheme() Scheme        { return twistedScheme{te: k.te} }
+func (k twistedPublicKey) Key() *ecdsa.PublicKey { return k.pub }
+
+func (k twistedPublicKey) Encrypt(rand io.Reader, m *big.Int) (*Ciphertext, error) {
+	r, err := randFieldElement(k.pub.Curve, rand)
+	if err != nil {
+		return nil, err
+	}
+	return k.te.EncryptWithNonce(k.pub, m, r)
+}
+
+func (k twistedPublicKey) EncryptUint32(rand io.Reader, m uint32) (*Ciphertext, error) {
+	return k.te.EncryptUint32(rand, k.pub, m)
+}
+
+func (k twistedPublicKey) EncryptInt32(rand io.Reader, m int32) (*Ciphertext, error) {
+	return k.te.EncryptInt32(rand, k.pub, m)
+}
+
+func (k twistedPublicKey) Rerandomize(rand io.Reader, c *Ciphertext) (*Ciphertext, error) {
+	return k.te.Rerandomize(rand, k.pub, c)
+}
+
+type twistedSecretKey struct {
+	*TwistedPrivateKey
+	te *TwistedElgamal
+}
+
+// NewTwistedSecretKey returns the [SecretKey] of a Twisted private key, the
+// Twisted context is derived from the key.
+func NewTwistedSecretKey(priv *TwistedPrivateKey) SecretKey {
+	return twistedSecretKey{priv, FromPrivateKey(priv)}
+}
+
+func (k twistedSecretKey) Public() PublicKey {
+	return twistedPublicKey{k.te, &k.PublicKey}
+}
@@ -0,0 +1,82 @@
+package sm2elgamal
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestScheme(t *testing.T) {
+	schemes := map[string]Scheme{
+		"standard": NewStandardScheme(),
+		"twisted":  NewTwistedScheme(te),
+	}
+	for name, scheme := range schemes {
+		t.Run(name, func(t *testing.T) {
+			sk, err := scheme.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pk := scheme.NewPublicKey(sk.Public().Key())
+			c1, err := pk.EncryptUint32(rand.Reader, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c2, err := sk.Public().EncryptInt32(rand.Reader, -30)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = scheme.Add(c1, c2); err != ErrPlaintextTypeMismatch {
+				t.Fatalf("expected ErrPlaintextTypeMismatch, got %v", err)
+			}
+			// an untyped ciphertext works with both uint32 and int32 ones
+			if c2, err = sk.Public().Encrypt(rand.Reader, big.NewInt(-30)); err != nil {
+				t.Fatal(err)
+			}
+			sum, err := scheme.Add(c1, c2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sum, err = scheme.AddPlain(sum, big.NewInt(5)); err != nil {
+				t.Fatal(err)
+			}
+			if sum, err = scheme.ScalarMult(sum, big.NewInt(3)); err != nil {
+				t.Fatal(err)
+			}
+			if sum, err = pk.Rerandomize(rand.Reader, sum); err != nil {
+				t.Fatal(err)
+			}
+			if v, err := sk.DecryptUint32(sum); err != nil || v != 225 {
+				t.Fatalf("expected 225, got %v, %v", v, err)
+			}
+			diff, err := scheme.Sub(c2, c1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff, err = scheme.Neg(diff); err != nil {
+				t.Fatal(err)
+			}
+			if v, err := sk.DecryptInt32(diff); err != nil || v != 130 {
+				t.Fatalf("expected 130, got %v, %v", v, err)
+			}
+			if v, err := DecryptUint32DH(sk, c1); err != nil || v != 100 {
+				t.Fatalf("expected 100, got %v, %v", v, err)
+			}
+		})
+	}
+}
+
+func TestSchemeSecretKeys(t *testing.T) {
+	twisted, err := NewTwistedScheme(te).GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk := NewTwistedSecretKey(twisted.(twistedSecretKey).TwistedPrivateKey)
+	c, err := sk.Public().EncryptUint32(rand.Reader, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := twisted.DecryptUint32(c); err != nil || v != 7 {
+		t.Fatalf("expected 7, got %v, %v", v, err)
+	}
+}
@@ -0,0 +1,109 @@
+package sm2elgamal
+
+import (
+	"context"
+	"crypto/elliptic"
+	"math"
+	"math/big"
+)
+
+// maxSessionGiantSteps is the number of giant steps covering the whole uint64 range.
+var maxSessionGiantSteps = uint64(math.MaxUint64)/uint64(babySteps) + 1
+
+// DecryptionSession is a resumable baby-step giant-step search of the plaintext
+// of a ciphertext. It searches m from zero upwards, one giant step (2^21 values)
+// at a time, and keeps its state between calls, so the search can go on past
+// the uint32 range in chunks or in the background.
+//
+// A DecryptionSession is not safe for concurrent use.
+type DecryptionSession struct {
+	curve elliptic.Curve
+	x, y  *big.Int // mG - steps*babySteps*G, to be looked up
+	steps uint64   // giant steps done
+	value uint64
+	found bool
+}
+
+// NewDecryptionSession creates a decryption session of ciphertext, no search is done yet.
+func NewDecryptionSession(priv DHKey, ciphertext *Ciphertext) (*DecryptionSession, error) {
+	x, y, err := messagePoint(priv, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return &DecryptionSession{curve: priv.GetCurve(), x: x, y: y}, nil
+}
+
+// Searched returns X so that the plaintext is known not to be in [0, X) unless
+// it has been found. It is math.MaxUint64 once the whole uint64 range is searched.
+func (s *DecryptionSession) Searched() uint64 {
+	if s.steps >= maxSessionGiantSteps {
+		return math.MaxUint64
+	}
+	return s.steps * uint64(babySteps)
+}
+
+// Done reports whether the plaintext is found or the whole uint64 range is searched.
+func (s *DecryptionSession) Done() bool {
+	return s.found || s.steps >= maxSessionGiantSteps
+}
+
+// Result returns the plaintext and true if it is found.
+func (s *DecryptionSession) Result() (uint64, bool) {
+	return s.value, s.found
+}
+
+// Step runs at most n giant steps, it returns true if the session is done.
+func (s *DecryptionSession) Step(n int) (bool, error) {
+	if s.Done() {
+		return true, nil
+	}
+	table, err := lookupTable()
+	if err != nil {
+		return false, err
+	}
+	s.step(table, n)
+	return s.Done(), nil
+}
+
+func (s *DecryptionSession) step(table map[string]uint32, n int) {
+	for ; n > 0 && !s.Done(); n-- {
+		if s.x.Sign() == 0 && s.y.Sign() == 0 {
+			s.value, s.found = s.steps*uint64(babySteps), true
+			return
+		}
+		c := elliptic.MarshalCompressed(s.curve, s.x, s.y)
+		if v, prs := table[string(c[:poinCompressionLen])]; prs {
+			// the table only keeps point prefixes, which collide in a long search
+			vx, vy := s.curve.ScalarBaseMult(new(big.Int).SetUint64(uint64(v)).Bytes())
+			if vx.Cmp(s.x) == 0 && vy.Cmp(s.y) == 0 {
+				s.value, s.found = s.steps*uint64(babySteps)+uint64(v), true
+				return
+			}
+		}
+		s.x, s.y = s.curve.Add(s.x, s.y, giantBaseX, giantBaseY)
+		s.steps++
+	}
+}
+
+// Search continues the search until the plaintext is found or [0, limit) is
+// searched, it returns ErrOverflow in the latter case, and Searched tells how
+// far the search went. The context is checked between giant steps, and its
+// error is returned if it is done before the search ends.
+func (s *DecryptionSession) Search(ctx context.Context, limit uint64) (uint64, error) {
+	if !s.found && s.Searched() < limit {
+		table, err := lookupTable()
+		if err != nil {
+			return 0, err
+		}
+		for !s.found && s.Searched() < limit {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+			s.step(table, 1)
+		}
+	}
+	if !s.found {
+		return 0, ErrOverflow
+	}
+	return s.value, nil
+}
@@ -0,0 +1,81 @@
+package sm2elgamal
+
+import (
+	"context"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func TestDecryptionSession(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	c1, err := EncryptUint32(rand.Reader, &priv.PublicKey, 0xffffffff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := EncryptUint32(rand.Reader, &priv.PublicKey, 0xffffffff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c3, err := EncryptUint32(rand.Reader, &priv.PublicKey, uint32(3*babySteps))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := new(Ciphertext).Sum(c1, c2, c3)
+	expected := uint64(0xffffffff)*2 + uint64(3*babySteps)
+
+	s, err := NewDecryptionSession(NewPrivateKey(priv), sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Search(context.Background(), 1<<32); err != ErrOverflow {
+		t.Fatal("should be overflow error")
+	}
+	if s.Searched() != 1<<32 {
+		t.Fatalf("expected searched %x, got %x", uint64(1<<32), s.Searched())
+	}
+	if done, err := s.Step(giantSteps); err != nil || done {
+		t.Fatalf("unexpected step result %v, %v", done, err)
+	}
+	if s.Searched() != 1<<33 {
+		t.Fatalf("expected searched %x, got %x", uint64(1<<33), s.Searched())
+	}
+	v, err := s.Search(context.Background(), 1<<34)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != expected {
+		t.Fatalf("expected %x, got %x", expected, v)
+	}
+	if r, ok := s.Result(); !ok || r != expected || !s.Done() {
+		t.Fatalf("unexpected result %x, %v", r, ok)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s, err = NewDecryptionSession(NewPrivateKey(priv), sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Search(ctx, 1<<34); err != context.Canceled {
+		t.Fatalf("expected context canceled, got %v", err)
+	}
+}
+
+func TestDecryptionSessionPrefixCollision(t *testing.T) {
+	curve := sm2.P256()
+	x, y := curve.ScalarBaseMult([]byte{7})
+	c := elliptic.MarshalCompressed(curve, x, y)
+	// a table entry of 5 sharing the prefix of 7G
+	table := map[string]uint32{string(c[:poinCompressionLen]): 5}
+	s := &DecryptionSession{curve: curve, x: x, y: y}
+	s.step(table, 1)
+	if _, found := s.Result(); found {
+		t.Fatal("should not accept a prefix collision")
+	}
+	if s.Searched() != uint64(babySteps) {
+		t.Fatalf("expected the search to go on, searched %d", s.Searched())
+	}
+}
@@ -0,0 +1,223 @@
+package sm2elgamal
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/emmansun/gmsm/sm2"
+	"github.com/emmansun/gmsm/sm3"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// The lookup table file layout:
+//
+//	magic      [8]byte  "SM2EGTBL"
+//	version    uint8
+//	curve      uint8 length-prefixed curve name
+//	generator  uint8 length-prefixed compressed base point
+//	steps      uint32   baby steps, entries are 1*G ... (steps-1)*G
+//	prefixLen  uint8    bytes kept of each compressed point
+//	entries    uint32 length-prefixed (steps-1)*prefixLen bytes
+//	digest     [32]byte SM3 digest of all the preceding bytes
+const (
+	lookupTableMagic   = "SM2EGTBL"
+	lookupTableVersion = 1
+)
+
+// lookupTableFile is the path of the lookup table file.
+const lookupTableFile = "sm2_lookup_table.bin"
+
+var babyLookupTable struct {
+	sync.Mutex
+	table    map[string]uint32
+	idle     time.Duration
+	lastUsed time.Time
+	timer    *time.Timer
+	timerGen uint64 // invalidates the callbacks of stopped timers
+}
+
+// lookupTable returns the baby-step lookup table, loading it if it is not in memory.
+// The callers should get it once per decryption, the returned map stays valid
+// even if the table is released meanwhile.
+func lookupTable() (map[string]uint32, error) {
+	babyLookupTable.Lock()
+	defer babyLookupTable.Unlock()
+	if babyLookupTable.table == nil {
+		bin, err := os.ReadFile(lookupTableFile)
+		if err != nil {
+			return nil, err
+		}
+		table, err := parseLookupTable(sm2.P256(), bin)
+		if err != nil {
+			return nil, err
+		}
+		babyLookupTable.table = table
+	}
+	babyLookupTable.lastUsed = time.Now()
+	if babyLookupTable.idle > 0 && babyLookupTable.timer == nil {
+		startIdleTimer(babyLookupTable.idle)
+	}
+	return babyLookupTable.table, nil
+}
+
+// ReleaseLookupTable drops the in-memory lookup table, it will be loaded again
+// on next decryption. Decryptions in progress are not affected.
+func ReleaseLookupTable() {
+	babyLookupTable.Lock()
+	defer babyLookupTable.Unlock()
+	babyLookupTable.table = nil
+	stopIdleTimer()
+}
+
+// SetLookupTableIdleTimeout makes the lookup table be released after it has
+// not been used for d. Zero or negative d keeps the table in memory, which is
+// the default.
+func SetLookupTableIdleTimeout(d time.Duration) {
+	babyLookupTable.Lock()
+	defer babyLookupTable.Unlock()
+	babyLookupTable.idle = d
+	stopIdleTimer()
+	if d > 0 && babyLookupTable.table != nil {
+		startIdleTimer(d)
+	}
+}
+
+// startIdleTimer and stopIdleTimer must be called with babyLookupTable locked.
+func startIdleTimer(d time.Duration) {
+	babyLookupTable.timerGen++
+	gen := babyLookupTable.timerGen
+	babyLookupTable.timer = time.AfterFunc(d, func() {
+		releaseIdleLookupTable(gen)
+	})
+}
+
+func stopIdleTimer() {
+	if babyLookupTable.timer != nil {
+		babyLookupTable.timer.Stop()
+		babyLookupTable.timer = nil
+		babyLookupTable.timerGen++
+	}
+}
+
+func releaseIdleLookupTable(gen uint64) {
+	babyLookupTable.Lock()
+	defer babyLookupTable.Unlock()
+	if gen != babyLookupTable.timerGen {
+		return
+	}
+	babyLookupTable.timer = nil
+	if babyLookupTable.table == nil {
+		return
+	}
+	if elapsed := time.Since(babyLookupTable.lastUsed); elapsed < babyLookupTable.idle {
+		startIdleTimer(babyLookupTable.idle - elapsed)
+		return
+	}
+	babyLookupTable.table = nil
+}
+
+// ErrInvalidLookupTable is returned when the lookup table file is malformed,
+// corrupted or does not match the parameters of this package.
+var ErrInvalidLookupTable = errors.New("invalid lookup table")
+
+// WriteLookupTable generates the baby-step lookup table of SM2 curve and writes it to w.
+func WriteLookupTable(w io.Writer) error {
+	curve := sm2.P256()
+	params := curve.Params()
+	var b cryptobyte.Builder
+	b.AddBytes([]byte(lookupTableMagic))
+	b.AddUint8(lookupTableVersion)
+	b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes([]byte(params.Name))
+	})
+	b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(elliptic.MarshalCompressed(curve, params.Gx, params.Gy))
+	})
+	b.AddUint32(uint32(babySteps))
+	b.AddUint8(uint8(poinCompressionLen))
+	b.AddUint32LengthPrefixed(func(b *cryptobyte.Builder) {
+		x, y := params.Gx, params.Gy
+		for i := 1; i < babySteps; i++ {
+			if i > 1 {
+				x, y = curve.Add(x, y, params.Gx, params.Gy)
+			}
+			b.AddBytes(elliptic.MarshalCompressed(curve, x, y)[:poinCompressionLen])
+		}
+	})
+	bin, err := b.Bytes()
+	if err != nil {
+		return err
+	}
+	digest := sm3.Sum(bin)
+	if _, err = w.Write(bin); err != nil {
+		return err
+	}
+	_, err = w.Write(digest[:])
+	return err
+}
+
+// parseLookupTable verifies the lookup table file content and builds the
+// map from point prefix to its scalar.
+func parseLookupTable(curve elliptic.Curve, bin []byte) (map[string]uint32, error) {
+	var (
+		magic, name, generator, entries []byte
+		version, prefixLen              uint8
+		steps, entriesLen               uint32
+	)
+	input := cryptobyte.String(bin)
+	if !input.ReadBytes(&magic, len(lookupTableMagic)) || string(magic) != lookupTableMagic {
+		return nil, fmt.Errorf("%w: missing file header, regenerate it with generator.go", ErrInvalidLookupTable)
+	}
+	if !input.ReadUint8(&version) || version != lookupTableVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidLookupTable, version)
+	}
+	if len(bin) < len(lookupTableMagic)+1+sm3.Size {
+		return nil, fmt.Errorf("%w: file is truncated", ErrInvalidLookupTable)
+	}
+	body, sum := bin[:len(bin)-sm3.Size], bin[len(bin)-sm3.Size:]
+	if digest := sm3.Sum(body); !bytes.Equal(digest[:], sum) {
+		return nil, fmt.Errorf("%w: digest mismatch, the file is truncated or corrupted", ErrInvalidLookupTable)
+	}
+
+	input = cryptobyte.String(body[len(lookupTableMagic)+1:])
+	if !input.ReadUint8LengthPrefixed((*cryptobyte.String)(&name)) ||
+		!input.ReadUint8LengthPrefixed((*cryptobyte.String)(&generator)) ||
+		!input.ReadUint32(&steps) ||
+		!input.ReadUint8(&prefixLen) ||
+		!input.ReadUint32(&entriesLen) ||
+		!input.ReadBytes(&entries, int(entriesLen)) ||
+		!input.Empty() {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidLookupTable)
+	}
+
+	params := curve.Params()
+	if string(name) != params.Name {
+		return nil, fmt.Errorf("%w: curve is %q, expected %q", ErrInvalidLookupTable, name, params.Name)
+	}
+	if !bytes.Equal(generator, elliptic.MarshalCompressed(curve, params.Gx, params.Gy)) {
+		return nil, fmt.Errorf("%w: generator mismatch", ErrInvalidLookupTable)
+	}
+	if steps != uint32(babySteps) {
+		return nil, fmt.Errorf("%w: %d baby steps, expected %d", ErrInvalidLookupTable, steps, babySteps)
+	}
+	if int(prefixLen) != poinCompressionLen {
+		return nil, fmt.Errorf("%w: prefix length is %d, expected %d", ErrInvalidLookupTable, prefixLen, poinCompressionLen)
+	}
+	if len(entries) != (babySteps-1)*poinCompressionLen {
+		return nil, fmt.Errorf("%w: %d bytes of entries, expected %d", ErrInvalidLookupTable, len(entries), (babySteps-1)*poinCompressionLen)
+	}
+
+	table := make(map[string]uint32, babySteps-1)
+	for i := 0; i < babySteps-1; i++ {
+		p := entries[:poinCompressionLen]
+		entries = entries[poinCompressionLen:]
+		table[string(p)] = uint32(i + 1)
+	}
+	return table, nil
+}
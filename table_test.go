@@ -0,0 +1,117 @@
+package sm2elgamal
+
+import (
+	"crypto/rand"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emmansun/gmsm/sm2"
+	"github.com/emmansun/gmsm/sm3"
+)
+
+func TestParseLookupTable(t *testing.T) {
+	bin, err := os.ReadFile("sm2_lookup_table.bin")
+	if err != nil {
+		t.Skip(err)
+	}
+	table, err := parseLookupTable(sm2.P256(), bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table) != babySteps-1 {
+		t.Fatalf("lookup table is incorrect, %x", len(table))
+	}
+
+	resign := func(b []byte) []byte {
+		digest := sm3.Sum(b[:len(b)-sm3.Size])
+		copy(b[len(b)-sm3.Size:], digest[:])
+		return b
+	}
+	nameOffset := len(lookupTableMagic) + 2
+	entriesOffset := len(bin) - sm3.Size - (babySteps-1)*poinCompressionLen
+	cases := []struct {
+		name string
+		bin  []byte
+	}{
+		{"legacy", bin[entriesOffset : len(bin)-sm3.Size]},
+		{"truncated", bin[:len(bin)-1]},
+		{"corrupted", func() []byte {
+			b := append([]byte{}, bin...)
+			b[len(b)/2] ^= 1
+			return b
+		}()},
+		{"version", func() []byte {
+			b := append([]byte{}, bin...)
+			b[len(lookupTableMagic)] = 2
+			return resign(b)
+		}()},
+		{"curve", func() []byte {
+			b := append([]byte{}, bin...)
+			copy(b[nameOffset:], "sm2p256v2")
+			return resign(b)
+		}()},
+	}
+	for _, c := range cases {
+		if _, err := parseLookupTable(sm2.P256(), c.bin); !errors.Is(err, ErrInvalidLookupTable) {
+			t.Errorf("%s: expected ErrInvalidLookupTable, got %v", c.name, err)
+		}
+	}
+}
+
+func lookupTableLoaded() bool {
+	babyLookupTable.Lock()
+	defer babyLookupTable.Unlock()
+	return babyLookupTable.table != nil
+}
+
+func TestLookupTableLifecycle(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	ciphertext, err := EncryptUint32(rand.Reader, &priv.PublicKey, uint32(babySteps)+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypt := func() {
+		t.Helper()
+		v, err := DecryptUint32(priv, ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != uint32(babySteps)+1 {
+			t.Fatalf("expected %x, got %x", uint32(babySteps)+1, v)
+		}
+	}
+	decrypt()
+	ReleaseLookupTable()
+	if lookupTableLoaded() {
+		t.Fatal("lookup table should be released")
+	}
+	decrypt()
+	if !lookupTableLoaded() {
+		t.Fatal("lookup table should be reloaded")
+	}
+
+	SetLookupTableIdleTimeout(100 * time.Millisecond)
+	defer SetLookupTableIdleTimeout(0)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := DecryptUint32(priv, ciphertext); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if !lookupTableLoaded() {
+		t.Fatal("lookup table should not be released before idle timeout")
+	}
+	time.Sleep(500 * time.Millisecond)
+	if lookupTableLoaded() {
+		t.Fatal("lookup table should be released after idle timeout")
+	}
+	decrypt()
+}
@@ -0,0 +1,160 @@
+package sm2elgamal
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+
+	"github.com/emmansun/gmsm/sm3"
+)
+
+// SchemeID identifies the encryption scheme of a ciphertext.
+type SchemeID uint8
+
+const (
+	// SchemeStandard is the standard EC-ElGamal scheme.
+	SchemeStandard SchemeID = iota + 1
+	// SchemeTwisted is the Twisted EC-ElGamal scheme.
+	SchemeTwisted
+)
+
+var (
+	// ErrSchemeMismatch is returned when ciphertexts or keys of different schemes are used together.
+	ErrSchemeMismatch = errors.New("scheme mismatch")
+	// ErrPlaintextTypeMismatch is returned when ciphertexts of different plaintext
+	// types are used together, or a ciphertext is decrypted as another type.
+	ErrPlaintextTypeMismatch = errors.New("plaintext type mismatch")
+	// ErrKeyMismatch is returned when ciphertexts of different keys are used
+	// together, or a ciphertext is decrypted with another key.
+	ErrKeyMismatch = errors.New("key mismatch")
+)
+
+// keyFingerprintLen is the length of the public key fingerprint.
+const keyFingerprintLen = 8
+
+// tags binds a ciphertext to its scheme, plaintext type and key. The zero
+// value of each field means unknown, which matches any value, so that
+// ciphertexts built by [NewCiphertext] or [TrivialEncrypt] work with all.
+//
+// All the tags are checked when ciphertexts are combined or decrypted, with
+// one exception: the difference or the negation of a uint32 ciphertext may
+// be negative, so it has no plaintext type, and can be decrypted as int32.
+type tags struct {
+	scheme SchemeID
+	ptype  PlaintextType
+	key    []byte // public key fingerprint
+}
+
+// PublicKeyFingerprint returns the fingerprint of pub carried by ciphertexts,
+// it is the first 8 bytes of the SM3 digest of the compressed public key.
+func PublicKeyFingerprint(pub *ecdsa.PublicKey) []byte {
+	digest := sm3.Sum(elliptic.MarshalCompressed(pub.Curve, pub.X, pub.Y))
+	return digest[:keyFingerprintLen]
+}
+
+// Scheme returns the scheme of the ciphertext, zero if unknown.
+func (c *Ciphertext) Scheme() SchemeID {
+	return c.scheme
+}
+
+// PlaintextType returns the plaintext type of the ciphertext, zero if unknown.
+func (c *Ciphertext) PlaintextType() PlaintextType {
+	return c.ptype
+}
+
+// KeyFingerprint returns the fingerprint of the public key the ciphertext is
+// encrypted with, nil if unknown.
+func (c *Ciphertext) KeyFingerprint() []byte {
+	return bytes.Clone(c.key)
+}
+
+// mergeTags returns the tags of the combination of two ciphertexts.
+func mergeTags(a, b tags) (tags, error) {
+	if a.scheme == 0 {
+		a.scheme = b.scheme
+	} else if b.scheme != 0 && a.scheme != b.scheme {
+		return tags{}, ErrSchemeMismatch
+	}
+	if a.ptype == 0 {
+		a.ptype = b.ptype
+	} else if b.ptype != 0 && a.ptype != b.ptype {
+		return tags{}, ErrPlaintextTypeMismatch
+	}
+	if a.key == nil {
+		a.key = b.key
+	} else if b.key != nil && !bytes.Equal(a.key, b.key) {
+		return tags{}, ErrKeyMismatch
+	}
+	return a, nil
+}
+
+// checkPlaintextType returns ErrPlaintextTypeMismatch if c is known to be of
+// another plaintext type.
+func checkPlaintextType(c *Ciphertext, ptype PlaintextType) error {
+	if c != nil && c.ptype != 0 && c.ptype != ptype {
+		return ErrPlaintextTypeMismatch
+	}
+	return nil
+}
+
+// commonTags returns the tags shared by all the values, the others are unknown.
+func commonTags(values []*Ciphertext) tags {
+	t := values[0].tags
+	for _, v := range values[1:] {
+		if t.scheme != v.scheme {
+			t.scheme = 0
+		}
+		if t.ptype != v.ptype {
+			t.ptype = 0
+		}
+		if !bytes.Equal(t.key, v.key) {
+			t.key = nil
+		}
+	}
+	return t
+}
+
+// signed returns c, or a copy of it without the plaintext type if it is
+// uint32, for the operations whose result may be negative, see [tags].
+func (c *Ciphertext) signed() *Ciphertext {
+	if c.ptype != PlaintextUint32 {
+		return c
+	}
+	ret := *c
+	ret.ptype = 0
+	return &ret
+}
+
+// checkKey returns an error if c is known to be of another scheme or key
+// than priv.
+func checkKey(priv DHKey, c *Ciphertext) error {
+	scheme, pub := keyInfo(priv)
+	if scheme != 0 && c.scheme != 0 && scheme != c.scheme {
+		return ErrSchemeMismatch
+	}
+	if pub != nil && c.key != nil && !bytes.Equal(PublicKeyFingerprint(pub), c.key) {
+		return ErrKeyMismatch
+	}
+	return nil
+}
+
+// keyInfo returns the scheme and the public key of priv if they are known.
+func keyInfo(priv DHKey) (SchemeID, *ecdsa.PublicKey) {
+	switch k := priv.(type) {
+	case *sm2PrivateKey:
+		return SchemeStandard, &k.PublicKey
+	case *TwistedPrivateKey:
+		return SchemeTwisted, &k.PublicKey
+	case standardSecretKey:
+		return SchemeStandard, &k.priv.PublicKey
+	case twistedSecretKey:
+		return SchemeTwisted, &k.PublicKey
+	case interface{ Public() crypto.PublicKey }:
+		if pub, ok := k.Public().(*ecdsa.PublicKey); ok && pub.X != nil {
+			return 0, pub
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,182 @@
+package sm2elgamal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func TestCiphertextTags(t *testing.T) {
+	std, _ := sm2.GenerateKey(rand.Reader)
+	other, _ := sm2.GenerateKey(rand.Reader)
+	c1, _ := EncryptUint32(rand.Reader, &std.PublicKey, 1)
+	c2, _ := EncryptInt32(rand.Reader, &std.PublicKey, 2)
+	c3, _ := EncryptUint32(rand.Reader, &other.PublicKey, 3)
+	c4, _ := priv.EncryptUint32(rand.Reader, 4)
+
+	if c1.Scheme() != SchemeStandard || c1.PlaintextType() != PlaintextUint32 ||
+		!bytes.Equal(c1.KeyFingerprint(), PublicKeyFingerprint(&std.PublicKey)) {
+		t.Fatal("unexpected tags of standard ciphertext")
+	}
+	if c4.Scheme() != SchemeTwisted || c2.PlaintextType() != PlaintextInt32 {
+		t.Fatal("unexpected tags")
+	}
+
+	cases := []struct {
+		a, b     *Ciphertext
+		expected error
+	}{
+		{c1, c2, ErrPlaintextTypeMismatch},
+		{c1, c3, ErrKeyMismatch},
+		{c1, c4, ErrSchemeMismatch},
+	}
+	for i, c := range cases {
+		if _, err := Add(c.a, c.b); err != c.expected {
+			t.Fatalf("case %d: expected %v, got %v", i, c.expected, err)
+		}
+		if _, err := Sub(c.a, c.b); err != c.expected {
+			t.Fatalf("case %d: expected %v, got %v", i, c.expected, err)
+		}
+		if _, err := Sum(c.a, c.a, c.b); err != c.expected {
+			t.Fatalf("case %d: expected %v, got %v", i, c.expected, err)
+		}
+	}
+
+	if _, err := DecryptInt32(std, c1); err != ErrPlaintextTypeMismatch {
+		t.Fatalf("expected ErrPlaintextTypeMismatch, got %v", err)
+	}
+	if _, err := NewDecryptor().DecryptUint32(NewPrivateKey(std), c2); err != ErrPlaintextTypeMismatch {
+		t.Fatalf("expected ErrPlaintextTypeMismatch, got %v", err)
+	}
+	// the difference of uint32 ciphertexts may be negative, so it is untyped
+	diff, err := Sub(c1, c1)
+	if err != nil || diff.PlaintextType() != 0 {
+		t.Fatalf("expected untyped difference, got %v", err)
+	}
+	if diff, err = Sub(diff, c1); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := DecryptInt32(std, diff); err != nil || v != -1 {
+		t.Fatalf("expected -1, got %v, %v", v, err)
+	}
+	if _, err := DecryptUint32(other, c1); err != ErrKeyMismatch {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+	if _, err := NewDecryptor().DecryptUint32(priv, c1); err != ErrSchemeMismatch {
+		t.Fatalf("expected ErrSchemeMismatch, got %v", err)
+	}
+
+	// untagged ciphertexts match any tags
+	sum, err := Add(TrivialEncrypt(big.NewInt(5)), c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Scheme() != SchemeStandard || sum.PlaintextType() != PlaintextUint32 || sum.KeyFingerprint() == nil {
+		t.Fatal("tags should be kept")
+	}
+	if sum, err = ScalarMult(sum, big.NewInt(2)); err != nil {
+		t.Fatal(err)
+	}
+	if sum, err = Rerandomize(rand.Reader, &std.PublicKey, sum); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := DecryptUint32(std, sum); err != nil || v != 12 {
+		t.Fatalf("expected 12, got %v, %v", v, err)
+	}
+	neg, err := Neg(c2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := DecryptInt32(std, neg); err != nil || v != -2 {
+		t.Fatalf("expected -2, got %v, %v", v, err)
+	}
+}
+
+func TestCiphertextTagsMarshal(t *testing.T) {
+	c, _ := priv.EncryptInt32(rand.Reader, -5)
+	der, err := Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := Unmarshal(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c2.Scheme() != SchemeTwisted || c2.PlaintextType() != PlaintextInt32 ||
+		!bytes.Equal(c2.KeyFingerprint(), PublicKeyFingerprint(&priv.PublicKey)) {
+		t.Fatal("tags are not kept")
+	}
+	if _, err = priv.DecryptUint32(c2); err != ErrPlaintextTypeMismatch {
+		t.Fatalf("expected ErrPlaintextTypeMismatch, got %v", err)
+	}
+
+	// untagged ciphertexts are encoded as before
+	untagged, err := NewCiphertext(c.Curve(), c.C1Bytes(), c.C2Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err = Marshal(untagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(der) != 2+2*(2+33) {
+		t.Fatalf("unexpected untagged encoding %x", der)
+	}
+	if c2, err = Unmarshal(der); err != nil {
+		t.Fatal(err)
+	}
+	if c2.Scheme() != 0 || c2.PlaintextType() != 0 || c2.KeyFingerprint() != nil {
+		t.Fatal("should be untagged")
+	}
+	if v, err := priv.DecryptInt32(c2); err != nil || v != -5 {
+		t.Fatalf("expected -5, got %v, %v", v, err)
+	}
+
+	// unknown scheme
+	bad := append([]byte{0x30, byte(len(der) - 2 + 5)}, der[2:]...)
+	bad = append(bad, 0xa0, 0x03, 0x02, 0x01, 0x03)
+	if _, err = Unmarshal(bad); err == nil {
+		t.Fatal("should reject unknown scheme")
+	}
+}
+
+func TestCiphertextMethodsTags(t *testing.T) {
+	std, _ := sm2.GenerateKey(rand.Reader)
+	other, _ := sm2.GenerateKey(rand.Reader)
+	c1, _ := EncryptUint32(rand.Reader, &std.PublicKey, 1)
+	c2, _ := EncryptInt32(rand.Reader, &std.PublicKey, 2)
+	c3, _ := EncryptUint32(rand.Reader, &other.PublicKey, 3)
+	c4, _ := priv.EncryptUint32(rand.Reader, 4)
+	bounded, _ := WithBound(c1, big.NewInt(0), big.NewInt(math.MaxUint32))
+
+	// conflicting plaintext types or bounds are dropped
+	sum := new(Ciphertext).Add(c1, c2)
+	if sum.PlaintextType() != 0 || sum.Scheme() != SchemeStandard || sum.KeyFingerprint() == nil {
+		t.Fatal("only the plaintext type should be dropped")
+	}
+	if v, err := DecryptUint32(std, sum); err != nil || v != 3 {
+		t.Fatalf("expected 3, got %v, %v", v, err)
+	}
+	prod := new(Ciphertext).ScalarMultUint32(bounded, 2)
+	if _, _, ok := prod.Bound(); ok || prod.PlaintextType() != PlaintextUint32 {
+		t.Fatal("only the exceeded bound should be dropped")
+	}
+
+	// conflicting schemes or keys panic
+	mustPanic := func(name string, f func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("%s should panic", name)
+			}
+		}()
+		f()
+	}
+	mustPanic("Add of different keys", func() { new(Ciphertext).Add(c1, c3) })
+	mustPanic("Sub of different keys", func() { new(Ciphertext).Sub(c1, c3) })
+	mustPanic("Sum of different schemes", func() { new(Ciphertext).Sum(c1, c2, c4) })
+}
@@ -35,6 +35,11 @@ func (priv *TwistedPrivateKey) GetD() *big.Int {
 	return priv.D
 }
 
+func (priv *TwistedPrivateKey) DH(x, y *big.Int) (*big.Int, *big.Int, error) {
+	dx, dy := priv.ScalarMult(x, y, priv.D.Bytes())
+	return dx, dy, nil
+}
+
 func (priv *TwistedPrivateKey) Equal(x crypto.PrivateKey) bool {
 	xx, ok := x.(*TwistedPrivateKey)
 	if !ok {
@@ -117,48 +122,89 @@ func (te *TwistedElgamal) EncryptUint32(random io.Reader, pub *ecdsa.PublicKey,
 	if err != nil {
 		return nil, err
 	}
+	ret := te.encrypt(pub, new(big.Int).SetUint64(uint64(m)), r)
+	ret.ptype = PlaintextUint32
+	return ret, nil
+}
+
+// EncryptInt32 encrypts m with the publickey.
+func (te *TwistedElgamal) EncryptInt32(random io.Reader, pub *ecdsa.PublicKey, m int32) (*Ciphertext, error) {
+	r, err := randFieldElement(pub.Curve, random)
+	if err != nil {
+		return nil, err
+	}
+	ret := te.encrypt(pub, getFieldValue(pub.Curve, m), r)
+	ret.ptype = PlaintextInt32
+	return ret, nil
+}
+
+// encrypt returns (rP, rH + mG), m must be in [0, N).
+func (te *TwistedElgamal) encrypt(pub *ecdsa.PublicKey, m, r *big.Int) *Ciphertext {
 	// D = rP
 	x1, y1 := pub.Curve.ScalarMult(pub.X, pub.Y, r.Bytes())
 	// C = rH
 	x11, y11 := pub.Curve.ScalarMult(te.X, te.Y, r.Bytes())
 
 	var x2, y2 *big.Int
-	if m == 0 {
+	if m.Sign() == 0 {
 		x2 = big.NewInt(0)
 		y2 = big.NewInt(0)
 	} else {
-		x2, y2 = pub.Curve.ScalarBaseMult(big.NewInt(int64(m)).Bytes())
+		x2, y2 = pub.Curve.ScalarBaseMult(m.Bytes())
 	}
 	// C = rH + mG = r*d*(d^(-1)H) + mG = r*dP + mG = d*rP + mG = dD + mG
 	// mG = C - dD
 	x2, y2 = pub.Curve.Add(x11, y11, x2, y2)
 
-	return &Ciphertext{pub.Curve, elliptic.MarshalCompressed(pub.Curve, x1, y1), elliptic.MarshalCompressed(pub.Curve, x2, y2)}, nil
+	ret := newCiphertext(pub.Curve, x1, y1, x2, y2)
+	ret.scheme, ret.key = SchemeTwisted, PublicKeyFingerprint(pub)
+	return ret
 }
 
-// EncryptInt32 encrypts m with the publickey.
-func (te *TwistedElgamal) EncryptInt32(random io.Reader, pub *ecdsa.PublicKey, m int32) (*Ciphertext, error) {
+// Rerandomize returns a fresh ciphertext of the same plaintext by adding an
+// encryption of zero to c, that is (D + r'P, C + r'H) with a new random r'.
+// The result can't be linked to c by anyone without the private key.
+func (te *TwistedElgamal) Rerandomize(random io.Reader, pub *ecdsa.PublicKey, c *Ciphertext) (*Ciphertext, error) {
 	r, err := randFieldElement(pub.Curve, random)
 	if err != nil {
 		return nil, err
 	}
-	// D = rP
-	x1, y1 := pub.Curve.ScalarMult(pub.X, pub.Y, r.Bytes())
-	// C = rH
-	x11, y11 := pub.Curve.ScalarMult(te.X, te.Y, r.Bytes())
+	zero := te.encrypt(pub, new(big.Int), r)
+	zero.bound = newInterval(0, 0)
+	return Add(c, zero)
+}
 
-	var x2, y2 *big.Int
-	if m == 0 {
-		x2 = big.NewInt(0)
-		y2 = big.NewInt(0)
-	} else {
-		mVal := getFieldValue(pub.Curve, m)
-		x2, y2 = pub.Curve.ScalarBaseMult(mVal.Bytes())
+// EncryptWithNonce encrypts m with the publickey and the caller supplied
+// randomness r, see [EncryptWithNonce].
+func (te *TwistedElgamal) EncryptWithNonce(pub *ecdsa.PublicKey, m, r *big.Int) (*Ciphertext, error) {
+	if err := checkNonce(pub.Curve, r); err != nil {
+		return nil, err
 	}
-	// C = rH + mG
-	x2, y2 = pub.Curve.Add(x11, y11, x2, y2)
+	return te.encrypt(pub, new(big.Int).Mod(m, pub.Curve.Params().N), r), nil
+}
 
-	return &Ciphertext{pub.Curve, elliptic.MarshalCompressed(pub.Curve, x1, y1), elliptic.MarshalCompressed(pub.Curve, x2, y2)}, nil
+// EncryptReturningOpening encrypts m with the publickey, and returns the
+// opening of the ciphertext too. The C part of the ciphertext is a Pedersen
+// commitment of m, which is opened by the returned opening.
+func (te *TwistedElgamal) EncryptReturningOpening(random io.Reader, pub *ecdsa.PublicKey, m *big.Int) (*Ciphertext, *Opening, error) {
+	r, err := randFieldElement(pub.Curve, random)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := te.EncryptWithNonce(pub, m, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, &Opening{M: new(big.Int).Set(m), R: r}, nil
+}
+
+// VerifyOpening reports whether c is the encryption of o.M with randomness o.R.
+func (te *TwistedElgamal) VerifyOpening(pub *ecdsa.PublicKey, c *Ciphertext, o *Opening) bool {
+	if o == nil || o.M == nil {
+		return false
+	}
+	expected, err := te.EncryptWithNonce(pub, o.M, o.R)
+	return err == nil && sameCiphertext(c, expected)
 }
 
 // EncryptInt32 encrypts m with the publickey.
@@ -1,6 +1,7 @@
 package sm2elgamal
 
 import (
+	"bytes"
 	"crypto/rand"
 	"testing"
 )
@@ -71,3 +72,24 @@ func TestTwistedEncryptDecryptInt32(t *testing.T) {
 		testTwistedEncryptDecryptInt32(t, priv, int32(-i*babySteps))
 	}
 }
+
+func TestTwistedRerandomize(t *testing.T) {
+	ciphertext, err := priv.EncryptUint32(rand.Reader, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext2, err := FromPrivateKey(priv).Rerandomize(rand.Reader, &priv.PublicKey, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(ciphertext.c1, ciphertext2.c1) || bytes.Equal(ciphertext.c2, ciphertext2.c2) {
+		t.Fatal("should be different")
+	}
+	v, err := priv.DecryptUint32(ciphertext2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 10 {
+		t.Fatalf("expected %d, got %d", 10, v)
+	}
+}
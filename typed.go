@@ -0,0 +1,196 @@
+package sm2elgamal
+
+import (
+	"context"
+	"crypto/elliptic"
+	"io"
+	"math/big"
+	"reflect"
+)
+
+// Integer is the plaintext type of [TypedCiphertext].
+type Integer interface {
+	~uint8 | ~uint16 | ~uint32 | ~uint64 | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+// TypedCiphertext is a ciphertext of a plaintext of type T, so that the
+// compiler makes sure it is only combined with and decrypted as T.
+//
+// Only uint32 and int32 have a plaintext type tag, see [Ciphertext.PlaintextType],
+// the underlying ciphertexts of the other types are untagged, so the type is
+// lost once they are marshaled, and [NewTypedCiphertext] accepts them as any type.
+type TypedCiphertext[T Integer] struct {
+	c *Ciphertext
+}
+
+// integerInfo returns the bit size and the signedness of T.
+func integerInfo[T Integer]() (bits int, signed bool) {
+	var zero T
+	return reflect.TypeFor[T]().Bits(), ^zero < 0
+}
+
+// plaintextTypeOf returns the plaintext type tag of T, zero if there is none.
+func plaintextTypeOf[T Integer]() PlaintextType {
+	switch bits, signed := integerInfo[T](); {
+	case bits == 32 && signed:
+		return PlaintextInt32
+	case bits == 32:
+		return PlaintextUint32
+	}
+	return 0
+}
+
+func integerToBig[T Integer](m T) *big.Int {
+	if _, signed := integerInfo[T](); signed {
+		return big.NewInt(int64(m))
+	}
+	return new(big.Int).SetUint64(uint64(m))
+}
+
+// NewTypedCiphertext wraps c as a ciphertext of T, it returns
+// ErrPlaintextTypeMismatch if c is tagged as another type.
+func NewTypedCiphertext[T Integer](c *Ciphertext) (*TypedCiphertext[T], error) {
+	if _, _, _, _, err := c.points(); err != nil {
+		return nil, err
+	}
+	ptype := plaintextTypeOf[T]()
+	if c.ptype != 0 && c.ptype != ptype {
+		return nil, ErrPlaintextTypeMismatch
+	}
+	ret := *c
+	ret.ptype = ptype
+	return &TypedCiphertext[T]{&ret}, nil
+}
+
+// EncryptTyped encrypts m of type T with the public key of any scheme.
+func EncryptTyped[T Integer](random io.Reader, pub PublicKey, m T) (*TypedCiphertext[T], error) {
+	c, err := pub.Encrypt(random, integerToBig(m))
+	if err != nil {
+		return nil, err
+	}
+	c.ptype = plaintextTypeOf[T]()
+	return &TypedCiphertext[T]{c}, nil
+}
+
+// SumTyped returns cumulative sum value.
+func SumTyped[T Integer](values ...*TypedCiphertext[T]) (*TypedCiphertext[T], error) {
+	cs := make([]*Ciphertext, len(values))
+	for i, v := range values {
+		cs[i] = v.Ciphertext()
+	}
+	return wrapTyped[T](Sum(cs...))
+}
+
+func wrapTyped[T Integer](c *Ciphertext, err error) (*TypedCiphertext[T], error) {
+	if err != nil {
+		return nil, err
+	}
+	return &TypedCiphertext[T]{c}, nil
+}
+
+// Ciphertext returns the underlying ciphertext.
+func (c *TypedCiphertext[T]) Ciphertext() *Ciphertext {
+	if c == nil {
+		return nil
+	}
+	return c.c
+}
+
+// Add returns c + other.
+func (c *TypedCiphertext[T]) Add(other *TypedCiphertext[T]) (*TypedCiphertext[T], error) {
+	return wrapTyped[T](Add(c.Ciphertext(), other.Ciphertext()))
+}
+
+// Sub returns c - other.
+func (c *TypedCiphertext[T]) Sub(other *TypedCiphertext[T]) (*TypedCiphertext[T], error) {
+	return wrapTyped[T](Sub(c.Ciphertext(), other.Ciphertext()))
+}
+
+// ScalarMult returns k * c.
+func (c *TypedCiphertext[T]) ScalarMult(k T) (*TypedCiphertext[T], error) {
+	return wrapTyped[T](ScalarMult(c.Ciphertext(), integerToBig(k)))
+}
+
+// Decrypt decrypts the ciphertext, it returns ErrOverflow if the plaintext
+// is out of the range of T. uint32 and int32 are decrypted as [DecryptUint32]
+// and [DecryptInt32] do, so math.MinInt32 is out of range too. The time grows
+// with the magnitude of the plaintext, it is too long for the whole range of
+// uint64 and int64, use [TypedCiphertext.DecryptContext] to bound it.
+func (c *TypedCiphertext[T]) Decrypt(priv DHKey) (T, error) {
+	return c.DecryptContext(context.Background(), priv)
+}
+
+// DecryptContext is like [TypedCiphertext.Decrypt], the context is checked
+// between giant steps if T is not a 32-bit type.
+func (c *TypedCiphertext[T]) DecryptContext(ctx context.Context, priv DHKey) (T, error) {
+	bits, signed := integerInfo[T]()
+	switch {
+	case bits == 32 && signed:
+		v, err := decryptInt32(priv, c.Ciphertext())
+		return T(v), err
+	case bits == 32:
+		v, err := decryptUint32(priv, c.Ciphertext())
+		return T(v), err
+	}
+	x, y, err := messagePoint(priv, c.Ciphertext())
+	if err != nil {
+		return 0, err
+	}
+	r := &interval{new(big.Int), new(big.Int).Lsh(big.NewInt(1), uint(bits))}
+	if signed {
+		r.lo.Neg(r.hi.Rsh(r.hi, 1))
+	}
+	r.hi.Sub(r.hi, big.NewInt(1))
+	m, err := searchInterval(ctx, priv.GetCurve(), x, y, c.c.bound.intersect(r))
+	if err != nil {
+		return 0, err
+	}
+	if signed {
+		return T(m.Int64()), nil
+	}
+	return T(m.Uint64()), nil
+}
+
+// searchRange finds m in (-negLimit, posLimit) with (x, y) = mG, the positive
+// and the negative ranges are searched in turn, one giant step at a time.
+// It returns the magnitude of m and whether it is negative.
+func searchRange(ctx context.Context, curve elliptic.Curve, x, y *big.Int, posLimit, negLimit uint64) (uint64, bool, error) {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return 0, false, nil
+	}
+	table, err := lookupTable()
+	if err != nil {
+		return 0, false, err
+	}
+	pos := &DecryptionSession{curve: curve, x: x, y: y}
+	neg := &DecryptionSession{curve: curve, x: x, y: new(big.Int).Sub(curve.Params().P, y)}
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, false, err
+		}
+		searching := false
+		if pos.Searched() < posLimit && !pos.Done() {
+			pos.step(table, 1)
+			searching = true
+		}
+		if pos.found {
+			if pos.value >= posLimit {
+				return 0, false, ErrOverflow
+			}
+			return pos.value, false, nil
+		}
+		if neg.Searched() < negLimit && !neg.Done() {
+			neg.step(table, 1)
+			searching = true
+		}
+		if neg.found {
+			if neg.value >= negLimit {
+				return 0, false, ErrOverflow
+			}
+			return neg.value, true, nil
+		}
+		if !searching {
+			return 0, false, ErrOverflow
+		}
+	}
+}
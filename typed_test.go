@@ -0,0 +1,115 @@
+package sm2elgamal
+
+import (
+	"context"
+	"crypto/rand"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func testTyped[T Integer](t *testing.T, sk SecretKey, values ...T) {
+	t.Helper()
+	for _, m := range values {
+		c, err := EncryptTyped(rand.Reader, sk.Public(), m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := c.Decrypt(sk)
+		if err != nil {
+			t.Fatalf("%T %v: %v", m, m, err)
+		}
+		if v != m {
+			t.Fatalf("expected %v, got %v", m, v)
+		}
+	}
+}
+
+func TestTypedCiphertext(t *testing.T) {
+	std, _ := sm2.GenerateKey(rand.Reader)
+	sk := NewStandardSecretKey(std)
+	testTyped(t, sk, uint8(0), 1, math.MaxUint8)
+	testTyped(t, sk, uint16(0), 1, math.MaxUint16)
+	testTyped(t, sk, uint32(0), 1, math.MaxUint32)
+	testTyped(t, sk, uint64(0), 1, 1<<34+5)
+	testTyped(t, sk, int8(0), 1, -1, math.MinInt8, math.MaxInt8)
+	testTyped(t, sk, int16(0), 1, -1, math.MinInt16, math.MaxInt16)
+	testTyped(t, sk, int32(0), 1, -1, -math.MaxInt32, math.MaxInt32)
+	testTyped(t, sk, int64(0), 1, -1, -(1<<33 + 7), 1<<33+7)
+
+	twisted := NewTwistedSecretKey(priv)
+	testTyped(t, twisted, int16(-300), 300)
+}
+
+func TestTypedCiphertextOps(t *testing.T) {
+	sk := NewTwistedSecretKey(priv)
+	a, _ := EncryptTyped(rand.Reader, sk.Public(), int8(100))
+	b, _ := EncryptTyped(rand.Reader, sk.Public(), int8(-60))
+	sum, err := SumTyped(a, b, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := sum.Decrypt(sk); err != nil || v != -20 {
+		t.Fatalf("expected -20, got %v, %v", v, err)
+	}
+	if sum, err = sum.ScalarMult(-5); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := sum.Decrypt(sk); err != nil || v != 100 {
+		t.Fatalf("expected 100, got %v, %v", v, err)
+	}
+	if sum, err = sum.Add(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sum.Decrypt(sk); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+	if sum, err = b.Sub(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sum.Decrypt(sk); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+
+	u1, _ := EncryptTyped(rand.Reader, sk.Public(), uint16(1))
+	u2, _ := EncryptTyped(rand.Reader, sk.Public(), uint16(2))
+	diff, err := u1.Sub(u2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = diff.Decrypt(sk); err != ErrOverflow {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+}
+
+func TestNewTypedCiphertext(t *testing.T) {
+	c, _ := priv.EncryptInt32(rand.Reader, -7)
+	if _, err := NewTypedCiphertext[uint32](c); err != ErrPlaintextTypeMismatch {
+		t.Fatalf("expected ErrPlaintextTypeMismatch, got %v", err)
+	}
+	typed, err := NewTypedCiphertext[int32](c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := typed.Decrypt(priv); err != nil || v != -7 {
+		t.Fatalf("expected -7, got %v, %v", v, err)
+	}
+	untagged, _ := NewCiphertext(c.Curve(), c.C1Bytes(), c.C2Bytes())
+	typed64, err := NewTypedCiphertext[int64](untagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := typed64.Decrypt(priv); err != nil || v != -7 {
+		t.Fatalf("expected -7, got %v, %v", v, err)
+	}
+
+	// a huge uint64 plaintext takes too long, the search is bounded by the context
+	huge, _ := EncryptTyped(rand.Reader, NewTwistedSecretKey(priv).Public(), uint64(math.MaxUint64-1))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := huge.DecryptContext(ctx, priv); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+package sm2elgamal
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/emmansun/gmsm/sm2"
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/cryptobyte/asn1"
+)
+
+// ErrLengthMismatch is returned when vectors of different lengths are used together.
+var ErrLengthMismatch = errors.New("vector length mismatch")
+
+// EncryptedVector is a vector of ciphertexts of the same curve, scheme and key.
+type EncryptedVector struct {
+	elems []*Ciphertext
+}
+
+// NewEncryptedVector creates a vector of the ciphertexts, they must be of the
+// same curve and their tags must match, see [Ciphertext.Scheme].
+func NewEncryptedVector(elems []*Ciphertext) (*EncryptedVector, error) {
+	if _, err := vectorTags(elems); err != nil {
+		return nil, err
+	}
+	return &EncryptedVector{append([]*Ciphertext(nil), elems...)}, nil
+}
+
+// vectorTags checks the ciphertexts and returns their merged tags.
+func vectorTags(elems []*Ciphertext) (tags, error) {
+	if len(elems) == 0 {
+		return tags{}, ErrNoCiphertext
+	}
+	var t tags
+	for _, c := range elems {
+		if _, _, _, _, err := c.points(); err != nil {
+			return tags{}, err
+		}
+		if !sameCurve(elems[0].curve, c.curve) {
+			return tags{}, ErrCurveMismatch
+		}
+		var err error
+		if t, err = mergeTags(t, c.tags); err != nil {
+			return tags{}, err
+		}
+	}
+	return t, nil
+}
+
+// EncryptVectorUint32 encrypts the values with the public key of any scheme.
+func EncryptVectorUint32(random io.Reader, pub PublicKey, values []uint32) (*EncryptedVector, error) {
+	elems := make([]*Ciphertext, len(values))
+	for i, m := range values {
+		c, err := pub.EncryptUint32(random, m)
+		if err != nil {
+			return nil, err
+		}
+		elems[i] = c
+	}
+	return &EncryptedVector{elems}, nil
+}
+
+// EncryptVectorInt32 encrypts the values with the public key of any scheme.
+func EncryptVectorInt32(random io.Reader, pub PublicKey, values []int32) (*EncryptedVector, error) {
+	elems := make([]*Ciphertext, len(values))
+	for i, m := range values {
+		c, err := pub.EncryptInt32(random, m)
+		if err != nil {
+			return nil, err
+		}
+		elems[i] = c
+	}
+	return &EncryptedVector{elems}, nil
+}
+
+// Len returns the length of the vector.
+func (v *EncryptedVector) Len() int {
+	return len(v.elems)
+}
+
+// Elements returns the ciphertexts of the vector.
+func (v *EncryptedVector) Elements() []*Ciphertext {
+	return append([]*Ciphertext(nil), v.elems...)
+}
+
+// DecryptUint32 decrypts all the elements to uint32.
+func (v *EncryptedVector) DecryptUint32(priv DHKey) ([]uint32, error) {
+	ret := make([]uint32, len(v.elems))
+	for i, c := range v.elems {
+		m, err := decryptUint32(priv, c)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = m
+	}
+	return ret, nil
+}
+
+// DecryptInt32 decrypts all the elements to int32.
+func (v *EncryptedVector) DecryptInt32(priv DHKey) ([]int32, error) {
+	ret := make([]int32, len(v.elems))
+	for i, c := range v.elems {
+		m, err := decryptInt32(priv, c)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = m
+	}
+	return ret, nil
+}
+
+func (v *EncryptedVector) elementWise(other *EncryptedVector, op func(a, b *Ciphertext) (*Ciphertext, error)) (*EncryptedVector, error) {
+	if v == nil || other == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	if len(v.elems) != len(other.elems) {
+		return nil, ErrLengthMismatch
+	}
+	elems := make([]*Ciphertext, len(v.elems))
+	for i := range v.elems {
+		c, err := op(v.elems[i], other.elems[i])
+		if err != nil {
+			return nil, err
+		}
+		elems[i] = c
+	}
+	return &EncryptedVector{elems}, nil
+}
+
+// Add returns v + other element by element.
+func (v *EncryptedVector) Add(other *EncryptedVector) (*EncryptedVector, error) {
+	return v.elementWise(other, Add)
+}
+
+// Sub returns v - other element by element.
+func (v *EncryptedVector) Sub(other *EncryptedVector) (*EncryptedVector, error) {
+	return v.elementWise(other, Sub)
+}
+
+// MulPlain returns the vector of w[i] * v[i].
+func (v *EncryptedVector) MulPlain(w []int64) (*EncryptedVector, error) {
+	if v == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	if len(v.elems) != len(w) {
+		return nil, ErrLengthMismatch
+	}
+	elems := make([]*Ciphertext, len(v.elems))
+	for i, c := range v.elems {
+		prod, err := ScalarMult(c, big.NewInt(w[i]))
+		if err != nil {
+			return nil, err
+		}
+		elems[i] = prod
+	}
+	return &EncryptedVector{elems}, nil
+}
+
+// InnerProduct returns the encryption of the inner product of w and the
+// plaintext vector, that is the sum of w[i] * v[i].
+func (v *EncryptedVector) InnerProduct(w []int64) (*Ciphertext, error) {
+	prods, err := v.MulPlain(w)
+	if err != nil {
+		return nil, err
+	}
+	return Sum(prods.elems...)
+}
+
+// vectorPointLen returns the length of a point of curve in the vector
+// encoding, that is its compressed form, the point at infinity is encoded
+// as zeros.
+func vectorPointLen(curve elliptic.Curve) int {
+	return 1 + (curve.Params().BitSize+7)/8
+}
+
+// MarshalVector converts the vector to ASN.1 DER form.
+//
+//	EncryptedVector ::= SEQUENCE {
+//	  points         OCTET STRING, -- c1 || c2 of each element in compressed form
+//	  scheme         [0] INTEGER OPTIONAL,
+//	  plaintextType  [1] INTEGER OPTIONAL,
+//	  keyFingerprint [2] OCTET STRING OPTIONAL }
+//
+// The curve and the tags are shared by all the elements, so it is much
+// smaller than the elements marshaled one by one. Only the tags identical
+// in all the elements are kept. Like [Marshal], the bounds of the elements
+// are not kept.
+func MarshalVector(v *EncryptedVector) ([]byte, error) {
+	if v == nil {
+		return nil, ErrInvalidCiphertext
+	}
+	if _, err := vectorTags(v.elems); err != nil {
+		return nil, err
+	}
+	t := commonTags(v.elems)
+	pointLen := vectorPointLen(v.elems[0].curve)
+	points := make([]byte, 0, 2*pointLen*len(v.elems))
+	for _, c := range v.elems {
+		for _, p := range [][]byte{c.c1, c.c2} {
+			if len(p) == 1 {
+				p = make([]byte, pointLen)
+			}
+			points = append(points, p...)
+		}
+	}
+	var b cryptobyte.Builder
+	b.AddASN1(asn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1OctetString(points)
+		if t.scheme != 0 {
+			b.AddASN1(schemeTag, func(b *cryptobyte.Builder) {
+				b.AddASN1Uint64(uint64(t.scheme))
+			})
+		}
+		if t.ptype != 0 {
+			b.AddASN1(plaintextTypeTag, func(b *cryptobyte.Builder) {
+				b.AddASN1Uint64(uint64(t.ptype))
+			})
+		}
+		if t.key != nil {
+			b.AddASN1(keyFingerprintTag, func(b *cryptobyte.Builder) {
+				b.AddASN1OctetString(t.key)
+			})
+		}
+	})
+	return b.Bytes()
+}
+
+// UnmarshalVector parses the vector in ASN.1 DER form.
+func UnmarshalVector(der []byte) (*EncryptedVector, error) {
+	var (
+		inner         cryptobyte.String
+		points, key   []byte
+		scheme, ptype int
+		hasKey        bool
+	)
+	curve := sm2.P256()
+	pointLen := vectorPointLen(curve)
+	input := cryptobyte.String(der)
+	if !input.ReadASN1(&inner, asn1.SEQUENCE) ||
+		!input.Empty() ||
+		!inner.ReadASN1Bytes(&points, asn1.OCTET_STRING) ||
+		!inner.ReadOptionalASN1Integer(&scheme, schemeTag, 0) ||
+		!inner.ReadOptionalASN1Integer(&ptype, plaintextTypeTag, 0) ||
+		!inner.ReadOptionalASN1OctetString(&key, &hasKey, keyFingerprintTag) ||
+		!inner.Empty() ||
+		len(points) == 0 || len(points)%(2*pointLen) != 0 ||
+		scheme < 0 || scheme > int(SchemeTwisted) ||
+		ptype < 0 || ptype > int(PlaintextRange) ||
+		hasKey && len(key) != keyFingerprintLen {
+		return nil, errors.New("invalid asn1 format vector")
+	}
+	t := tags{scheme: SchemeID(scheme), ptype: PlaintextType(ptype)}
+	if hasKey {
+		t.key = key
+	}
+	elems := make([]*Ciphertext, len(points)/(2*pointLen))
+	zero := make([]byte, pointLen)
+	for i := range elems {
+		c := &Ciphertext{curve: curve, tags: t}
+		for _, p := range []*[]byte{&c.c1, &c.c2} {
+			*p, points = points[:pointLen], points[pointLen:]
+			if bytes.Equal(*p, zero) {
+				*p = []byte{0}
+			}
+		}
+		if _, _, _, _, err := c.points(); err != nil {
+			return nil, err
+		}
+		elems[i] = c
+	}
+	return &EncryptedVector{elems}, nil
+}
@@ -0,0 +1,157 @@
+package sm2elgamal
+
+import (
+	"crypto/rand"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/emmansun/gmsm/sm2"
+)
+
+func TestEncryptedVector(t *testing.T) {
+	sk := NewTwistedSecretKey(priv)
+	x, err := EncryptVectorInt32(rand.Reader, sk.Public(), []int32{1, -2, 3, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := EncryptVectorInt32(rand.Reader, sk.Public(), []int32{10, 20, -30, 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := x.Add(y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := sum.DecryptInt32(sk); err != nil || !slices.Equal(v, []int32{11, 18, -27, 40}) {
+		t.Fatalf("unexpected sum %v, %v", v, err)
+	}
+	diff, err := x.Sub(y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := diff.DecryptInt32(sk); err != nil || !slices.Equal(v, []int32{-9, -22, 33, -40}) {
+		t.Fatalf("unexpected difference %v, %v", v, err)
+	}
+	scaled, err := x.MulPlain([]int64{2, 3, -1, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := scaled.DecryptInt32(sk); err != nil || !slices.Equal(v, []int32{2, -6, -3, 0}) {
+		t.Fatalf("unexpected product %v, %v", v, err)
+	}
+	dot, err := y.InnerProduct([]int64{1, 2, 3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := sk.DecryptInt32(dot); err != nil || v != 120 {
+		t.Fatalf("expected 120, got %v, %v", v, err)
+	}
+
+	short, _ := EncryptVectorInt32(rand.Reader, sk.Public(), []int32{1})
+	if _, err = x.Add(short); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+	if _, err = x.InnerProduct([]int64{1}); err != ErrLengthMismatch {
+		t.Fatalf("expected ErrLengthMismatch, got %v", err)
+	}
+	u, _ := EncryptVectorUint32(rand.Reader, sk.Public(), []uint32{1, 2, 3, 4})
+	if _, err = x.Add(u); err != ErrPlaintextTypeMismatch {
+		t.Fatalf("expected ErrPlaintextTypeMismatch, got %v", err)
+	}
+	if _, err = NewEncryptedVector(append(x.Elements(), u.Elements()...)); err != ErrPlaintextTypeMismatch {
+		t.Fatalf("expected ErrPlaintextTypeMismatch, got %v", err)
+	}
+}
+
+func TestEncryptedVectorMarshal(t *testing.T) {
+	std, _ := sm2.GenerateKey(rand.Reader)
+	sk := NewStandardSecretKey(std)
+	v, err := EncryptVectorUint32(rand.Reader, sk.Public(), []uint32{0, 1, 2, 0xffffffff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an element with the point at infinity
+	inf, _ := ScalarMult(v.Elements()[1], big.NewInt(0))
+	inf, _ = AddPlain(inf, big.NewInt(5))
+	elems := append(v.Elements(), inf)
+	if v, err = NewEncryptedVector(elems); err != nil {
+		t.Fatal(err)
+	}
+	der, err := MarshalVector(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := 0
+	for _, c := range elems {
+		b, _ := Marshal(c)
+		size += len(b)
+	}
+	if len(der) >= size {
+		t.Fatalf("vector encoding of %d bytes is not smaller than %d bytes", len(der), size)
+	}
+	v2, err := UnmarshalVector(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v2.Len() != 5 {
+		t.Fatalf("expected 5 elements, got %d", v2.Len())
+	}
+	for i, c := range v2.Elements() {
+		if !sameCiphertext(c, elems[i]) {
+			t.Fatalf("element %d mismatch", i)
+		}
+		if c.Scheme() != SchemeStandard || c.PlaintextType() != PlaintextUint32 {
+			t.Fatalf("element %d: tags are not shared", i)
+		}
+	}
+	if m, err := v2.DecryptUint32(sk); err != nil || !slices.Equal(m, []uint32{0, 1, 2, 0xffffffff, 5}) {
+		t.Fatalf("unexpected values %v, %v", m, err)
+	}
+	other, _ := sm2.GenerateKey(rand.Reader)
+	if _, err = v2.DecryptUint32(NewPrivateKey(other)); err != ErrKeyMismatch {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+
+	if _, err = UnmarshalVector(der[:len(der)-1]); err == nil {
+		t.Fatal("should reject truncated vector")
+	}
+	if _, err = MarshalVector(&EncryptedVector{}); err != ErrNoCiphertext {
+		t.Fatalf("expected ErrNoCiphertext, got %v", err)
+	}
+}
+
+func TestEncryptedVectorMarshalTags(t *testing.T) {
+	sk := NewTwistedSecretKey(priv)
+	c1, _ := sk.Public().EncryptUint32(rand.Reader, 1)
+	c3, _ := sk.Public().EncryptUint32(rand.Reader, 3)
+	c2, err := Sub(c1, c3) // an untyped -2
+	if err != nil {
+		t.Fatal(err)
+	}
+	untagged, _ := NewCiphertext(c1.Curve(), c1.C1Bytes(), c1.C2Bytes())
+	v, err := NewEncryptedVector([]*Ciphertext{c1, c2, untagged})
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := MarshalVector(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = UnmarshalVector(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range v.Elements() {
+		if c.Scheme() != 0 || c.PlaintextType() != 0 || c.KeyFingerprint() != nil {
+			t.Fatalf("element %d: tags not shared by all the elements should be dropped", i)
+		}
+	}
+	if v, err := v.DecryptInt32(sk); err != nil || !slices.Equal(v, []int32{1, -2, 1}) {
+		t.Fatalf("unexpected values %v, %v", v, err)
+	}
+
+	if _, err = v.Add(nil); err != ErrInvalidCiphertext {
+		t.Fatalf("expected ErrInvalidCiphertext, got %v", err)
+	}
+}